@@ -0,0 +1,215 @@
+// Copyright 2023 DoltHub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// AutoFailover runs until it is stopped, health checking the current primary
+// every -interval. Once the primary has failed -failure-threshold
+// consecutive checks spanning at least -failure-window, it promotes the best
+// reachable standby, as promotestandby would, provided the guardrails in
+// AutoFailoverGuardrails allow it.
+type AutoFailover struct{}
+
+func (AutoFailover) LongRunning() {}
+
+func (cmd AutoFailover) Run(ctx context.Context, cfg *Config, cluster Cluster) error {
+	detector := FailureDetector{
+		Threshold: cfg.FailureThreshold,
+		Window:    cfg.FailureWindow,
+	}
+	var lastFailover time.Time
+
+	ticker := time.NewTicker(cfg.Interval)
+	defer ticker.Stop()
+
+	log.Printf("health checking the primary of %s every %v", cluster.Name(), cfg.Interval)
+	for {
+		if autoFailoverOnce(ctx, cfg, cluster, &detector, lastFailover) {
+			lastFailover = time.Now()
+		}
+		select {
+		case <-ctx.Done():
+			log.Printf("stopped health checking the primary of %s", cluster.Name())
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Makes one health check of the primary of |cluster| and fails over if
+// |detector| decides it has failed. Returns true if it promoted a standby.
+func autoFailoverOnce(ctx context.Context, cfg *Config, cluster Cluster, detector *FailureDetector, lastFailover time.Time) bool {
+	ctx, cancel := context.WithTimeout(ctx, cfg.Timeout)
+	defer cancel()
+
+	if wc, ok := cluster.(WatchableCluster); ok {
+		err := wc.Refresh(ctx)
+		if err != nil {
+			// The primary's pod may be the reason the cluster cannot
+			// be reloaded, so we keep health checking through the
+			// pods as they were last loaded.
+			log.Printf("WARNING: could not reload cluster %s; health checking the pods as last loaded: %v", cluster.Name(), err)
+		}
+	}
+
+	dbstates := LoadDBStates(ctx, cfg, cluster)
+	if ctx.Err() != nil {
+		return false
+	}
+
+	_, _, err := CurrentPrimaryAndEpoch(dbstates)
+	if err == nil {
+		detector.Observe(time.Now(), true)
+		return false
+	}
+	if numRole(dbstates, "primary") > 1 {
+		// Failing over would not help. This needs an operator.
+		log.Printf("WARNING: not health checking the primary: %v", err)
+		detector.Reset()
+		return false
+	}
+
+	failed := detector.Observe(time.Now(), false)
+	log.Printf("health check of the primary failed (%d/%d): %s", detector.Failures(), detector.Threshold, primaryFailure(dbstates))
+	if !failed {
+		return false
+	}
+
+	err = AutoFailoverGuardrails(cfg, dbstates, time.Since(lastFailover))
+	if err != nil {
+		log.Printf("WARNING: not failing over: %v", err)
+		return false
+	}
+
+	log.Printf("primary has failed %d health checks over %v; failing over", detector.Failures(), detector.FailingFor(time.Now()))
+	err = promoteStandby(ctx, cfg, dbstates)
+	if err != nil {
+		log.Printf("ERROR: failed to fail over: %v", err)
+		return false
+	}
+	detector.Reset()
+	return true
+}
+
+// Describes why no reachable pod in |dbstates| is in role primary.
+func primaryFailure(dbstates []DBState) string {
+	for _, state := range dbstates {
+		if state.Instance.Role() == RolePrimary && state.Err != nil {
+			return state.Err.Error()
+		}
+	}
+	var unreachable []string
+	for _, state := range dbstates {
+		if state.Err != nil {
+			unreachable = append(unreachable, state.Instance.Name())
+		}
+	}
+	if len(unreachable) > 0 {
+		return fmt.Sprintf("no reachable pod is in role primary; unreachable pods: %s", strings.Join(unreachable, ", "))
+	}
+	return "no reachable pod is in role primary"
+}
+
+// Returns the number of reachable servers in |dbstates| in |role|.
+func numRole(dbstates []DBState, role string) int {
+	n := 0
+	for _, state := range dbstates {
+		if state.Err == nil && state.Role == role {
+			n++
+		}
+	}
+	return n
+}
+
+// Returns an error describing why it is not safe to automatically promote a
+// standby, or nil if it is. Failing over is refused when fewer than half of
+// the pods are reachable, since the problem is more likely to be between
+// doltclusterctl and the cluster than with the primary; when fewer than
+// -min-reachable-standbys standbys are reachable; when a server is in
+// detected_broken_config; and within -failover-cooldown of the last
+// automatic failover.
+func AutoFailoverGuardrails(cfg *Config, dbstates []DBState, sinceLastFailover time.Duration) error {
+	reachable := 0
+	for _, state := range dbstates {
+		if state.Err == nil {
+			reachable++
+		}
+		if state.Role == "detected_broken_config" {
+			return fmt.Errorf("pod %s is in detected_broken_config. Run resolvebrokenconfig.", state.Instance.Name())
+		}
+	}
+	if reachable*2 < len(dbstates) {
+		return fmt.Errorf("only %d of %d pods are reachable", reachable, len(dbstates))
+	}
+	if standbys := numRole(dbstates, "standby"); standbys < cfg.MinReachableStandbys {
+		return fmt.Errorf("only %d standbys are reachable; -min-reachable-standbys is %d", standbys, cfg.MinReachableStandbys)
+	}
+	if sinceLastFailover < cfg.FailoverCooldown {
+		return fmt.Errorf("the last automatic failover was %v ago; -failover-cooldown is %v", sinceLastFailover.Round(time.Second), cfg.FailoverCooldown)
+	}
+	return nil
+}
+
+// FailureDetector decides when a series of failed health checks means the
+// primary has failed. It requires |Threshold| consecutive failures, the
+// first and last of which are at least |Window| apart, so that neither a
+// burst of quick failures nor a single slow one triggers a failover.
+type FailureDetector struct {
+	Threshold int
+	Window    time.Duration
+
+	failures     int
+	firstFailure time.Time
+}
+
+// Records the result of a health check made at |now|. Returns true if the
+// primary should be considered failed.
+func (d *FailureDetector) Observe(now time.Time, healthy bool) bool {
+	if healthy {
+		d.Reset()
+		return false
+	}
+	if d.failures == 0 {
+		d.firstFailure = now
+	}
+	d.failures++
+	return d.failures >= d.Threshold && now.Sub(d.firstFailure) >= d.Window
+}
+
+// Forgets all failures observed so far.
+func (d *FailureDetector) Reset() {
+	d.failures = 0
+	d.firstFailure = time.Time{}
+}
+
+// Returns the number of consecutive failures observed.
+func (d *FailureDetector) Failures() int {
+	return d.failures
+}
+
+// Returns how long the health checks have been failing as of |now|.
+func (d *FailureDetector) FailingFor(now time.Time) time.Duration {
+	if d.failures == 0 {
+		return 0
+	}
+	return now.Sub(d.firstFailure)
+}
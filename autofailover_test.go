@@ -0,0 +1,146 @@
+// Copyright 2023 DoltHub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFailureDetector(t *testing.T) {
+	start := time.Now()
+	t.Run("NeedsThreshold", func(t *testing.T) {
+		d := FailureDetector{Threshold: 3, Window: time.Second}
+		assert.False(t, d.Observe(start, false))
+		assert.False(t, d.Observe(start.Add(10*time.Second), false))
+		assert.True(t, d.Observe(start.Add(20*time.Second), false))
+	})
+	t.Run("NeedsWindow", func(t *testing.T) {
+		d := FailureDetector{Threshold: 3, Window: time.Minute}
+		assert.False(t, d.Observe(start, false))
+		assert.False(t, d.Observe(start.Add(10*time.Second), false))
+		assert.False(t, d.Observe(start.Add(20*time.Second), false))
+		assert.False(t, d.Observe(start.Add(30*time.Second), false))
+		assert.True(t, d.Observe(start.Add(time.Minute), false))
+		assert.Equal(t, 5, d.Failures())
+	})
+	t.Run("HealthyResets", func(t *testing.T) {
+		d := FailureDetector{Threshold: 2, Window: time.Second}
+		assert.False(t, d.Observe(start, false))
+		assert.False(t, d.Observe(start.Add(10*time.Second), true))
+		assert.Equal(t, 0, d.Failures())
+		assert.False(t, d.Observe(start.Add(20*time.Second), false))
+		assert.True(t, d.Observe(start.Add(30*time.Second), false))
+		assert.Equal(t, 10*time.Second, d.FailingFor(start.Add(30*time.Second)))
+	})
+}
+
+func TestAutoFailoverGuardrails(t *testing.T) {
+	cfg := &Config{MinReachableStandbys: 1, FailoverCooldown: time.Minute}
+	dead := DBState{
+		Instance: &mockInstance{name: "dolt-0", role: RolePrimary},
+		Err:      errors.New("connection refused"),
+	}
+	standby := func(name string) DBState {
+		return DBState{
+			Role:     "standby",
+			Epoch:    10,
+			Instance: &mockInstance{name: name, role: RoleStandby},
+		}
+	}
+	t.Run("Allowed", func(t *testing.T) {
+		err := AutoFailoverGuardrails(cfg, []DBState{dead, standby("dolt-1")}, time.Hour)
+		assert.NoError(t, err)
+	})
+	t.Run("MostPodsUnreachable", func(t *testing.T) {
+		unreachable := DBState{
+			Instance: &mockInstance{name: "dolt-2", role: RoleStandby},
+			Err:      errors.New("connection refused"),
+		}
+		err := AutoFailoverGuardrails(cfg, []DBState{dead, standby("dolt-1"), unreachable}, time.Hour)
+		assert.EqualError(t, err, "only 1 of 3 pods are reachable")
+	})
+	t.Run("MinReachableStandbys", func(t *testing.T) {
+		cfg := &Config{MinReachableStandbys: 2, FailoverCooldown: time.Minute}
+		err := AutoFailoverGuardrails(cfg, []DBState{dead, standby("dolt-1")}, time.Hour)
+		assert.EqualError(t, err, "only 1 standbys are reachable; -min-reachable-standbys is 2")
+	})
+	t.Run("Cooldown", func(t *testing.T) {
+		err := AutoFailoverGuardrails(cfg, []DBState{dead, standby("dolt-1")}, 30*time.Second)
+		assert.EqualError(t, err, "the last automatic failover was 30s ago; -failover-cooldown is 1m0s")
+	})
+	t.Run("DetectedBrokenConfig", func(t *testing.T) {
+		broken := standby("dolt-2")
+		broken.Role = "detected_broken_config"
+		err := AutoFailoverGuardrails(cfg, []DBState{dead, standby("dolt-1"), broken}, time.Hour)
+		assert.Error(t, err)
+	})
+}
+
+// An instance whose sql-server cannot be reached, for example because its
+// pod is gone. The error is permanent, so LoadDBState does not retry it.
+type unreachableInstance struct {
+	mockInstance
+}
+
+func (i *unreachableInstance) ConnectAddress(context.Context) (string, int, error) {
+	return "", 0, backoff.Permanent(errors.New("pod is terminating"))
+}
+
+// A WatchableCluster which cannot be reloaded.
+type unrefreshableCluster struct {
+	instances []Instance
+}
+
+func (c unrefreshableCluster) Name() string {
+	return "unrefreshable"
+}
+
+func (c unrefreshableCluster) NumReplicas() int {
+	return len(c.instances)
+}
+
+func (c unrefreshableCluster) Instance(i int) Instance {
+	return c.instances[i]
+}
+
+func (c unrefreshableCluster) Refresh(context.Context) error {
+	return errors.New("pod dolt/dolt-0 is missing")
+}
+
+func (c unrefreshableCluster) Watch(context.Context) (<-chan struct{}, error) {
+	return nil, nil
+}
+
+func TestAutoFailoverOnceRefreshFails(t *testing.T) {
+	cfg := &Config{Timeout: 10 * time.Second, MinReachableStandbys: 1}
+	cluster := unrefreshableCluster{instances: []Instance{
+		&unreachableInstance{mockInstance{name: "dolt/dolt-0", role: RolePrimary}},
+		&unreachableInstance{mockInstance{name: "dolt/dolt-1", role: RoleStandby}},
+	}}
+	detector := FailureDetector{Threshold: 2, Window: 0}
+
+	// The cluster cannot be reloaded, but the health check still runs
+	// against the pods as they were last loaded, and fails.
+	assert.False(t, autoFailoverOnce(context.Background(), cfg, cluster, &detector, time.Time{}))
+	assert.Equal(t, 1, detector.Failures())
+	assert.False(t, autoFailoverOnce(context.Background(), cfg, cluster, &detector, time.Time{}))
+	assert.Equal(t, 2, detector.Failures())
+}
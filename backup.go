@@ -0,0 +1,130 @@
+// Copyright 2023 DoltHub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+// Backup backs up every database from the most caught up standby, as chosen
+// by PickNextPrimary, to -backup-url. It prints a manifest of the source pod
+// and the branch heads it backed up. It fails if the primary reports the
+// standby more than -backup-max-lag behind, before or during the backup.
+type Backup struct{}
+
+// How many times to sync a database whose branch heads move during the sync
+// before settling for the heads from before the last one.
+const backupSyncAttempts = 3
+
+func (cmd Backup) Run(ctx context.Context, cfg *Config, cluster Cluster) error {
+	dbstates := LoadDBStates(ctx, cfg, cluster)
+
+	source := PickNextPrimary(dbstates)
+	if source == -1 {
+		return fmt.Errorf("cannot back up %s: failed to find a reachable standby", cluster.Name())
+	}
+	instance := dbstates[source].Instance
+
+	log.Printf("backing up from %s", instance.Name())
+
+	err := checkBackupLag(ctx, cfg, cluster, source)
+	if err != nil {
+		return fmt.Errorf("cannot back up from %s: %w", instance.Name(), err)
+	}
+
+	manifest := BackupManifest{
+		Source:  instance.Name(),
+		Epoch:   dbstates[source].Epoch,
+		Started: time.Now().UTC(),
+	}
+
+	names, err := LoadDatabaseNames(ctx, cfg, instance)
+	if err != nil {
+		return fmt.Errorf("cannot back up from %s: %w", instance.Name(), err)
+	}
+	for _, name := range names {
+		url := BackupDatabaseURL(cfg.BackupURL, name)
+		branches, err := CallBackupSyncURL(ctx, cfg, instance, name, url, backupSyncAttempts)
+		if err != nil {
+			return fmt.Errorf("error backing up database %s from %s to %s: %w", name, instance.Name(), url, err)
+		}
+		log.Printf("backed up database %s to %s", name, url)
+
+		err = checkBackupLag(ctx, cfg, cluster, source)
+		if err != nil {
+			return fmt.Errorf("backup of database %s from %s may be stale: %w", name, instance.Name(), err)
+		}
+
+		manifest.Databases = append(manifest.Databases, BackupManifestDatabase{
+			Database: name,
+			URL:      url,
+			Branches: branches,
+		})
+	}
+	manifest.Finished = time.Now().UTC()
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(manifest)
+}
+
+// Returns an error if the primary of |cluster| reports that the standby at
+// |standby| has a replication error or is more than -backup-max-lag behind
+// for any database, or does not report replicating to it at all. If there is
+// no primary, the standby cannot be behind.
+func checkBackupLag(ctx context.Context, cfg *Config, cluster Cluster, standby int) error {
+	dbstates := LoadDBStates(ctx, cfg, cluster)
+	if dbstates[standby].Err != nil {
+		return dbstates[standby].Err
+	}
+	primary, _, err := CurrentPrimaryAndEpoch(dbstates)
+	if err != nil {
+		log.Printf("WARNING: not checking replication lag: %v", err)
+		return nil
+	}
+	if problem := StandbyCaughtUpProblem(dbstates, primary, standby, cfg.BackupMaxLag); problem != "" {
+		return fmt.Errorf("%s is not caught up: %s", dbstates[standby].Instance.Name(), problem)
+	}
+	return nil
+}
+
+// Returns the URL of the backup of |database| under |base|.
+func BackupDatabaseURL(base, database string) string {
+	return strings.TrimSuffix(base, "/") + "/" + database
+}
+
+// A record of a backup taken by Backup.
+type BackupManifest struct {
+	// The name of the pod the backup was taken from.
+	Source    string                   `json:"source"`
+	Epoch     int                      `json:"epoch"`
+	Started   time.Time                `json:"started"`
+	Finished  time.Time                `json:"finished"`
+	Databases []BackupManifestDatabase `json:"databases"`
+}
+
+type BackupManifestDatabase struct {
+	Database string `json:"database"`
+	URL      string `json:"url"`
+	// The commit hash at the head of each branch which was backed up,
+	// keyed by branch name. The backup may also include later commits.
+	Branches map[string]string `json:"branches"`
+}
@@ -0,0 +1,174 @@
+// Copyright 2023 DoltHub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"maps"
+	"time"
+)
+
+// The database Bootstrap creates on the new primary, and drops again, to
+// check that replication works.
+const BootstrapCheckDatabase = "doltclusterctl_bootstrap_check"
+
+// Bootstrap assigns the first primary of a freshly deployed cluster. It
+// checks that the cluster is fresh, makes the chosen instance primary and
+// every other instance standby at the epoch after -bootstrap-epoch, applies
+// the labels, and checks that a write to the primary replicates to every
+// standby. dolt refuses to change the role of a server without raising its
+// epoch, so roles cannot be assigned at -bootstrap-epoch itself.
+type Bootstrap struct {
+	// The ordinal or the name of the instance to make primary.
+	Target string
+}
+
+func (cmd Bootstrap) Run(ctx context.Context, cfg *Config, cluster Cluster) error {
+	dbstates := LoadDBStates(ctx, cfg, cluster)
+	for _, state := range dbstates {
+		if state.Err != nil {
+			return fmt.Errorf("cannot bootstrap: %w", state.Err)
+		}
+	}
+
+	target, err := ResolveInstance(dbstates, cmd.Target)
+	if err != nil {
+		return fmt.Errorf("cannot bootstrap with %s as primary: %w", cmd.Target, err)
+	}
+	newPrimary := dbstates[target].Instance
+
+	heads := make([][]DatabaseHeads, len(dbstates))
+	for i, state := range dbstates {
+		heads[i], err = LoadHeads(ctx, cfg, state.Instance)
+		if err != nil {
+			return fmt.Errorf("cannot bootstrap: %w", err)
+		}
+	}
+
+	problems := BootstrapProblems(dbstates, heads, cfg.BootstrapEpoch)
+	if len(problems) > 0 && !cfg.Force {
+		return fmt.Errorf("refusing to bootstrap: %s. Run with -force to bootstrap anyway.", problems[0])
+	}
+	for _, p := range problems {
+		log.Printf("WARNING: %s; bootstrapping anyway because of -force", p)
+	}
+
+	epoch := cfg.BootstrapEpoch + 1
+	if highest := HighestEpoch(dbstates); highest >= epoch {
+		epoch = highest + 1
+	}
+
+	log.Printf("bootstrapping %s with %s as primary at epoch %d", cluster.Name(), newPrimary.Name(), epoch)
+
+	err = AssumeRoles(ctx, cfg, dbstates, target, epoch)
+	if err != nil {
+		return err
+	}
+
+	for i, state := range dbstates {
+		if i == target {
+			continue
+		}
+		err := state.Instance.MarkRoleStandby(ctx)
+		if err != nil {
+			return err
+		}
+	}
+	err = newPrimary.MarkRolePrimary(ctx)
+	if err != nil {
+		return err
+	}
+	log.Printf("applied primary label to %s and standby labels to the other pods", newPrimary.Name())
+
+	return checkReplication(ctx, cfg, dbstates, target)
+}
+
+// Writes a value to a new database on the primary, waits for every standby
+// to have it, and drops the database again.
+func checkReplication(ctx context.Context, cfg *Config, dbstates []DBState, primary int) error {
+	value := time.Now().UnixNano()
+	err := ExecStatements(ctx, cfg, dbstates[primary].Instance,
+		fmt.Sprintf("CREATE DATABASE `%s`", BootstrapCheckDatabase),
+		fmt.Sprintf("CREATE TABLE `%s`.`check` (pk int PRIMARY KEY, value bigint)", BootstrapCheckDatabase),
+		fmt.Sprintf("INSERT INTO `%s`.`check` VALUES (1, %d)", BootstrapCheckDatabase, value),
+	)
+	if err != nil {
+		return fmt.Errorf("error writing to the new primary: %w", err)
+	}
+	log.Printf("wrote to database %s on %s", BootstrapCheckDatabase, dbstates[primary].Instance.Name())
+
+	for i, state := range dbstates {
+		if i == primary {
+			continue
+		}
+		for {
+			got, err := loadBootstrapCheck(ctx, cfg, state.Instance)
+			if err == nil && got == value {
+				break
+			}
+			if ctx.Err() != nil {
+				return fmt.Errorf("the write to the new primary did not replicate to %s: %w", state.Instance.Name(), ctx.Err())
+			}
+			time.Sleep(500 * time.Millisecond)
+		}
+		log.Printf("write replicated to %s", state.Instance.Name())
+	}
+
+	return ExecStatements(ctx, cfg, dbstates[primary].Instance, fmt.Sprintf("DROP DATABASE `%s`", BootstrapCheckDatabase))
+}
+
+func loadBootstrapCheck(ctx context.Context, cfg *Config, instance Instance) (int64, error) {
+	db, err := OpenDB(ctx, cfg, instance)
+	if err != nil {
+		return 0, err
+	}
+	defer db.Close()
+	var value int64
+	err = db.QueryRowContext(ctx, fmt.Sprintf("SELECT value FROM `%s`.`check` WHERE pk = 1", BootstrapCheckDatabase)).Scan(&value)
+	return value, err
+}
+
+// Returns the reasons the cluster in |dbstates|, whose servers have the
+// databases in |heads|, does not look freshly deployed. A fresh cluster has
+// no server above |bootstrapEpoch| and either no user databases or the
+// same databases, with the same branch heads, on every server.
+func BootstrapProblems(dbstates []DBState, heads [][]DatabaseHeads, bootstrapEpoch int) []string {
+	var problems []string
+	for _, state := range dbstates {
+		if state.Epoch > bootstrapEpoch {
+			problems = append(problems, fmt.Sprintf("pod %s is at epoch %d, above -bootstrap-epoch %d", state.Instance.Name(), state.Epoch, bootstrapEpoch))
+		}
+	}
+	for i := 1; i < len(heads); i++ {
+		if !sameHeads(heads[0], heads[i]) {
+			problems = append(problems, fmt.Sprintf("pods %s and %s have different databases", dbstates[0].Instance.Name(), dbstates[i].Instance.Name()))
+		}
+	}
+	return problems
+}
+
+func sameHeads(a, b []DatabaseHeads) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Database != b[i].Database || !maps.Equal(a[i].Branches, b[i].Branches) {
+			return false
+		}
+	}
+	return true
+}
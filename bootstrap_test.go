@@ -0,0 +1,55 @@
+// Copyright 2023 DoltHub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBootstrapProblems(t *testing.T) {
+	dbstates := []DBState{
+		{Role: "primary", Epoch: 1, Instance: &mockInstance{name: "dolt-0"}},
+		{Role: "standby", Epoch: 1, Instance: &mockInstance{name: "dolt-1"}},
+	}
+	mydb := func(head string) []DatabaseHeads {
+		return []DatabaseHeads{{
+			Database: "mydb",
+			Branches: map[string]string{"main": head},
+		}}
+	}
+	t.Run("Fresh", func(t *testing.T) {
+		assert.Empty(t, BootstrapProblems(dbstates, make([][]DatabaseHeads, 2), 1))
+	})
+	t.Run("IdenticalDatabases", func(t *testing.T) {
+		assert.Empty(t, BootstrapProblems(dbstates, [][]DatabaseHeads{mydb("abc"), mydb("abc")}, 1))
+	})
+	t.Run("AboveBootstrapEpoch", func(t *testing.T) {
+		res := BootstrapProblems(dbstates, make([][]DatabaseHeads, 2), 0)
+		assert.Equal(t, []string{
+			"pod dolt-0 is at epoch 1, above -bootstrap-epoch 0",
+			"pod dolt-1 is at epoch 1, above -bootstrap-epoch 0",
+		}, res)
+	})
+	t.Run("MissingDatabase", func(t *testing.T) {
+		res := BootstrapProblems(dbstates, [][]DatabaseHeads{mydb("abc"), nil}, 1)
+		assert.Equal(t, []string{"pods dolt-0 and dolt-1 have different databases"}, res)
+	})
+	t.Run("DifferentHeads", func(t *testing.T) {
+		res := BootstrapProblems(dbstates, [][]DatabaseHeads{mydb("abc"), mydb("def")}, 1)
+		assert.Equal(t, []string{"pods dolt-0 and dolt-1 have different databases"}, res)
+	})
+}
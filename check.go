@@ -0,0 +1,191 @@
+// Copyright 2023 DoltHub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// The exit codes of monitoring plugins.
+const (
+	CheckOK       = 0
+	CheckWarning  = 1
+	CheckCritical = 2
+	CheckUnknown  = 3
+)
+
+var checkStates = []string{"OK", "WARNING", "CRITICAL", "UNKNOWN"}
+
+// Check evaluates the health of the cluster like a Nagios plugin. It prints
+// a one-line summary with perf data, followed by every problem it found,
+// and exits 0 for OK, 1 for WARNING, 2 for CRITICAL and 3 for UNKNOWN.
+type Check struct{}
+
+func (cmd Check) Run(ctx context.Context, cfg *Config, cluster Cluster) error {
+	dbstates := LoadDBStates(ctx, cfg, cluster)
+	res := EvaluateCheck(dbstates, cfg.MaxLag, cfg.RequireStandbys, cfg.FailOnCurrentError)
+	RenderCheck(os.Stdout, res)
+	if res.Code != CheckOK {
+		return &ExitError{Code: res.Code, Err: fmt.Errorf("cluster %s is %s: %s", cluster.Name(), checkStates[res.Code], res.Summary)}
+	}
+	return nil
+}
+
+func (cmd Check) ReportFailure(err error) int {
+	RenderCheck(os.Stdout, CheckResult{Code: CheckUnknown, Summary: err.Error()})
+	return CheckUnknown
+}
+
+type CheckProblem struct {
+	Code    int
+	Message string
+}
+
+type CheckResult struct {
+	// The most severe Code of the Problems, or CheckOK.
+	Code     int
+	Summary  string
+	Problems []CheckProblem
+	Perf     []string
+}
+
+// Evaluates the health of the cluster described by |dbstates|.
+//
+// It is CRITICAL if there is not exactly one reachable primary, if a server
+// is in detected_broken_config, or if fewer than |requireStandbys| servers
+// are reachable in role standby. It is WARNING if a server is unreachable
+// or labeled with a role other than its own, or if the primary reports a
+// standby more than |maxLag| behind or with unknown lag for a database. A
+// current_error on a standby is WARNING, or CRITICAL if
+// |failOnCurrentError|. It is UNKNOWN if no server is reachable.
+func EvaluateCheck(dbstates []DBState, maxLag time.Duration, requireStandbys int, failOnCurrentError bool) CheckResult {
+	var res CheckResult
+	add := func(code int, format string, args ...any) {
+		res.Problems = append(res.Problems, CheckProblem{Code: code, Message: fmt.Sprintf(format, args...)})
+	}
+
+	reachable, standbys := 0, 0
+	var primaries []int
+	for i, state := range dbstates {
+		name := state.Instance.Name()
+		if state.Err != nil {
+			add(CheckWarning, "pod %s is unreachable: %v", name, state.Err)
+			continue
+		}
+		reachable++
+		switch state.Role {
+		case "primary":
+			primaries = append(primaries, i)
+			if label := state.Instance.Role(); label != RolePrimary {
+				add(CheckWarning, "pod %s is in role primary but is labeled %s", name, label)
+			}
+		case "standby":
+			standbys++
+			if label := state.Instance.Role(); label != RoleStandby {
+				add(CheckWarning, "pod %s is in role standby but is labeled %s", name, label)
+			}
+		case "detected_broken_config":
+			add(CheckCritical, "pod %s is in detected_broken_config", name)
+		default:
+			add(CheckCritical, "pod %s is in unexpected role %s", name, state.Role)
+		}
+	}
+
+	if len(primaries) == 0 {
+		add(CheckCritical, "no reachable pod is in role primary")
+	} else if len(primaries) > 1 {
+		var names []string
+		for _, i := range primaries {
+			names = append(names, dbstates[i].Instance.Name())
+		}
+		add(CheckCritical, "more than one reachable pod is in role primary: %s", strings.Join(names, ", "))
+	}
+	if standbys < requireStandbys {
+		add(CheckCritical, "%d pods are reachable in role standby, fewer than -require-standbys %d", standbys, requireStandbys)
+	}
+
+	var lagMillis int64
+	currentErrors := 0
+	if len(primaries) == 1 {
+		for _, row := range dbstates[primaries[0]].Status {
+			if row.ReplicationLag.Valid && row.ReplicationLag.Int64 > lagMillis {
+				lagMillis = row.ReplicationLag.Int64
+			}
+			if row.CurrentError.Valid {
+				currentErrors++
+				code := CheckWarning
+				if failOnCurrentError {
+					code = CheckCritical
+				}
+				add(code, "database %s to standby %s has replication error: %s", row.Database, row.Remote, row.CurrentError.String)
+			} else if problem := CaughtUpProblem(row, maxLag); problem != "" {
+				add(CheckWarning, "database %s to standby %s %s, more than -max-lag %v", row.Database, row.Remote, problem, maxLag)
+			}
+		}
+	}
+
+	currentErrorsWarn, currentErrorsCrit := "0", ""
+	if failOnCurrentError {
+		currentErrorsWarn, currentErrorsCrit = "", "0"
+	}
+	res.Perf = []string{
+		fmt.Sprintf("reachable=%d;;;0;%d", reachable, len(dbstates)),
+		fmt.Sprintf("standbys=%d;;%d:;0;%d", standbys, requireStandbys, len(dbstates)),
+		fmt.Sprintf("max_lag=%dms;%d;;0", lagMillis, maxLag.Milliseconds()),
+		fmt.Sprintf("current_errors=%d;%s;%s;0", currentErrors, currentErrorsWarn, currentErrorsCrit),
+	}
+
+	if reachable == 0 {
+		res.Code = CheckUnknown
+		res.Summary = "no pod is reachable"
+		return res
+	}
+
+	worst := -1
+	for i, p := range res.Problems {
+		if p.Code > res.Code {
+			res.Code = p.Code
+			worst = i
+		}
+	}
+	if worst == -1 {
+		primary := dbstates[primaries[0]]
+		res.Summary = fmt.Sprintf("primary %s at epoch %d, %d standbys, max replication lag %dms", primary.Instance.Name(), primary.Epoch, standbys, lagMillis)
+	} else {
+		res.Summary = res.Problems[worst].Message
+		if len(res.Problems) > 1 {
+			res.Summary += fmt.Sprintf(" (and %d more problems)", len(res.Problems)-1)
+		}
+	}
+	return res
+}
+
+// Writes |res| to |w| in the output format of monitoring plugins: a status
+// line with perf data, followed by a line for every problem.
+func RenderCheck(w io.Writer, res CheckResult) {
+	line := fmt.Sprintf("DOLTCLUSTER %s - %s", checkStates[res.Code], res.Summary)
+	if len(res.Perf) > 0 {
+		line += " | " + strings.Join(res.Perf, " ")
+	}
+	fmt.Fprintln(w, line)
+	for _, p := range res.Problems {
+		fmt.Fprintf(w, "%s: %s\n", checkStates[p.Code], p.Message)
+	}
+}
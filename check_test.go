@@ -0,0 +1,108 @@
+// Copyright 2023 DoltHub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEvaluateCheck(t *testing.T) {
+	healthy := func() []DBState {
+		return []DBState{{
+			Role:     "primary",
+			Epoch:    3,
+			Instance: &mockInstance{name: "dolt-0", role: RolePrimary},
+			Status: []StatusRow{{
+				Database:       "mydb",
+				Remote:         "dolt-1",
+				ReplicationLag: sql.NullInt64{Valid: true, Int64: 0},
+			}},
+		}, {
+			Role:     "standby",
+			Epoch:    3,
+			Instance: &mockInstance{name: "dolt-1", role: RoleStandby},
+		}}
+	}
+	t.Run("OK", func(t *testing.T) {
+		res := EvaluateCheck(healthy(), 0, 1, false)
+		assert.Equal(t, CheckOK, res.Code)
+		assert.Equal(t, "primary dolt-0 at epoch 3, 1 standbys, max replication lag 0ms", res.Summary)
+		assert.Empty(t, res.Problems)
+	})
+	t.Run("Lagging", func(t *testing.T) {
+		dbstates := healthy()
+		dbstates[0].Status[0].ReplicationLag.Int64 = 1500
+		res := EvaluateCheck(dbstates, time.Second, 1, false)
+		assert.Equal(t, CheckWarning, res.Code)
+		assert.Equal(t, "database mydb to standby dolt-1 replication lag is 1500ms, more than -max-lag 1s", res.Summary)
+		assert.Contains(t, res.Perf, "max_lag=1500ms;1000;;0")
+	})
+	t.Run("CurrentError", func(t *testing.T) {
+		dbstates := healthy()
+		dbstates[0].Status[0].CurrentError = sql.NullString{Valid: true, String: "connection refused"}
+		assert.Equal(t, CheckWarning, EvaluateCheck(dbstates, 0, 1, false).Code)
+		assert.Equal(t, CheckCritical, EvaluateCheck(dbstates, 0, 1, true).Code)
+	})
+	t.Run("TooFewStandbys", func(t *testing.T) {
+		dbstates := healthy()
+		dbstates[1] = DBState{Instance: &mockInstance{name: "dolt-1"}, Err: errors.New("connection refused")}
+		res := EvaluateCheck(dbstates, 0, 1, false)
+		assert.Equal(t, CheckCritical, res.Code)
+		assert.Equal(t, "0 pods are reachable in role standby, fewer than -require-standbys 1 (and 1 more problems)", res.Summary)
+	})
+	t.Run("UnreachableStandbyNotRequired", func(t *testing.T) {
+		dbstates := healthy()
+		dbstates[1] = DBState{Instance: &mockInstance{name: "dolt-1"}, Err: errors.New("connection refused")}
+		res := EvaluateCheck(dbstates, 0, 0, false)
+		assert.Equal(t, CheckWarning, res.Code)
+	})
+	t.Run("NoPrimary", func(t *testing.T) {
+		dbstates := healthy()
+		dbstates[0].Role = "detected_broken_config"
+		res := EvaluateCheck(dbstates, 0, 1, false)
+		assert.Equal(t, CheckCritical, res.Code)
+		assert.Equal(t, "pod dolt-0 is in detected_broken_config (and 1 more problems)", res.Summary)
+	})
+	t.Run("NoneReachable", func(t *testing.T) {
+		res := EvaluateCheck([]DBState{
+			{Instance: &mockInstance{name: "dolt-0"}, Err: errors.New("connection refused")},
+			{Instance: &mockInstance{name: "dolt-1"}, Err: errors.New("connection refused")},
+		}, 0, 1, false)
+		assert.Equal(t, CheckUnknown, res.Code)
+		assert.Equal(t, "no pod is reachable", res.Summary)
+	})
+}
+
+func TestRenderCheck(t *testing.T) {
+	var buf bytes.Buffer
+	RenderCheck(&buf, CheckResult{
+		Code:    CheckWarning,
+		Summary: "pod dolt-1 is unreachable: connection refused",
+		Problems: []CheckProblem{{
+			Code:    CheckWarning,
+			Message: "pod dolt-1 is unreachable: connection refused",
+		}},
+		Perf: []string{"reachable=1;;;0;2", "standbys=0;;0:;0;2"},
+	})
+	assert.Equal(t, `DOLTCLUSTER WARNING - pod dolt-1 is unreachable: connection refused | reachable=1;;;0;2 standbys=0;;0:;0;2
+WARNING: pod dolt-1 is unreachable: connection refused
+`, buf.String())
+}
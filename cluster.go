@@ -24,6 +24,16 @@ const (
 	RoleStandby Role = 2
 )
 
+func (r Role) String() string {
+	switch r {
+	case RolePrimary:
+		return "primary"
+	case RoleStandby:
+		return "standby"
+	}
+	return "unknown"
+}
+
 type Instance interface {
 	// The name of the instance. A human-readable description which means
 	// something to an operator familiar with the deployment and the
@@ -86,3 +96,103 @@ type Cluster interface {
 	// |[0, NumReplicas())|.
 	Instance(int) Instance
 }
+
+// A Cluster whose deployment state can be reloaded and watched while a
+// long-running doltclusterctl command is running against it. Instances can
+// be restarted, relabeled and rescheduled by the deployment control plane
+// at any time.
+type WatchableCluster interface {
+	Cluster
+
+	// Reloads the replicas and their traffic roles from the service
+	// registry and deployment control plane. Instances previously
+	// returned from Instance() should not be used after a Refresh. If
+	// Refresh fails, the cluster and its instances are left as they were
+	// last loaded.
+	Refresh(context.Context) error
+
+	// Returns a channel which receives a value whenever the deployment
+	// or one of its instances changes. Changes which happen in quick
+	// succession may be coalesced. The channel stops receiving values
+	// once the context is done.
+	Watch(context.Context) (<-chan struct{}, error)
+}
+
+// A Cluster whose sql-server image can be changed. Instances pick up a new
+// image when they are restarted, and not before.
+type UpgradableCluster interface {
+	Cluster
+
+	// The image new instances of the deployment will run.
+	Image() string
+
+	// The image the instance with the given ordinal is currently running.
+	InstanceImage(int) string
+
+	// Changes the image new instances of the deployment will run. Fails
+	// if the deployment control plane would restart instances itself
+	// when the image changes.
+	SetImage(context.Context, string) error
+}
+
+// An Instance which is scheduled onto a node of the deployment, and which can
+// be evicted from it so that it is rescheduled.
+type EvictableInstance interface {
+	Instance
+
+	// The name of the node the instance is scheduled on.
+	NodeName() string
+
+	// Asks the deployment control plane to evict this instance, honoring
+	// any disruption budgets. Blocks until the instance is rescheduled
+	// and reports ready, like Restart.
+	Evict(context.Context) error
+}
+
+// An Instance whose storage usage can be measured.
+type DiskUsageInstance interface {
+	Instance
+
+	// The number of bytes used by the volumes holding the instance's
+	// databases.
+	DiskUsage(context.Context) (int64, error)
+}
+
+// An Instance whose storage can be thrown away and recreated empty, so that
+// it is reseeded from the primary by replication.
+type ReseedableInstance interface {
+	Instance
+
+	// Deletes this instance along with its storage. Blocks until the
+	// instance is recreated with empty storage and reports ready, like
+	// Restart.
+	Reseed(context.Context) error
+}
+
+// An Instance whose sql-server the running doltclusterctl reaches at an
+// address other than its Hostname(), for example through a tunnel. The
+// Hostname() is still the one the other instances use to reach it.
+type ConnectableInstance interface {
+	Instance
+
+	// The host and port at which the running doltclusterctl can connect
+	// to this instance's sql-server. May set up whatever is needed to
+	// reach it, so it can fail.
+	ConnectAddress(context.Context) (string, int, error)
+}
+
+// A Cluster whose number of replicas can be changed. Replicas are added and
+// removed at the highest ordinals.
+type ScalableCluster interface {
+	Cluster
+
+	// The hostname at which the instance with the given ordinal can be
+	// reached, including for ordinals the cluster does not have yet.
+	MemberHostname(int) string
+
+	// Changes the number of replicas. Blocks until the removed instances
+	// are gone and the added instances report ready, like Restart.
+	// Instances previously returned from Instance() should not be used
+	// after a Scale.
+	Scale(context.Context, int) error
+}
@@ -15,9 +15,16 @@
 package main
 
 import (
+	"bufio"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -25,6 +32,54 @@ type Command interface {
 	Run(context.Context, *Config, Cluster) error
 }
 
+// Implemented by commands which run until they are stopped, instead of
+// within -timeout.
+type LongRunningCommand interface {
+	Command
+	LongRunning()
+}
+
+// Implemented by commands which do not operate on a running cluster. They
+// are run with a nil Cluster, without connecting to Kubernetes.
+type StandaloneCommand interface {
+	Command
+	Standalone()
+}
+
+// Implemented by commands which ask for confirmation on stdin unless -yes
+// is given.
+type ConfirmingCommand interface {
+	Command
+	Confirms()
+}
+
+// Implemented by commands which report failures themselves, such as
+// failing to load the cluster, instead of exiting 1.
+type FailureReportingCommand interface {
+	Command
+	// Reports |err| and returns the code to exit with.
+	ReportFailure(err error) int
+}
+
+// Returned by commands which have already reported their result and which
+// should exit with Code.
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExitError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+// ApplyPrimaryLabels labels the primary pod primary and every other pod
+// standby. With -mark-unknown, it instead removes the role label from
+// standbys which are unreachable, in detected_broken_config or more than
+// -mark-unknown-lag behind, and restores it once they recover.
 type ApplyPrimaryLabels struct{}
 
 func (cmd ApplyPrimaryLabels) Run(ctx context.Context, cfg *Config, cluster Cluster) error {
@@ -34,13 +89,23 @@ func (cmd ApplyPrimaryLabels) Run(ctx context.Context, cfg *Config, cluster Clus
 			log.Printf("WARNING: error loading role and epoch for pod %s: %v", state.Instance.Name(), state.Err)
 		}
 	}
+	return ApplyLabels(ctx, cfg, dbstates)
+}
 
+// Applies the role labels to the instances in |dbstates|, as
+// ApplyPrimaryLabels does.
+func ApplyLabels(ctx context.Context, cfg *Config, dbstates []DBState) error {
 	// Find current primary across the pods.
 	currentprimary, _, err := CurrentPrimaryAndEpoch(dbstates)
 	if err != nil {
 		return fmt.Errorf("cannot apply primary labels: %w", err)
 	}
 
+	var unhealthy map[int]string
+	if cfg.MarkUnknown {
+		unhealthy = UnhealthyStandbys(dbstates, currentprimary, cfg.MarkUnknownLag)
+	}
+
 	// Apply the pod labels.
 	for i, state := range dbstates {
 		instance := state.Instance
@@ -52,6 +117,14 @@ func (cmd ApplyPrimaryLabels) Run(ctx context.Context, cfg *Config, cluster Clus
 				}
 				log.Printf("applied primary label to %s", instance.Name())
 			}
+		} else if reason, ok := unhealthy[i]; ok {
+			if instance.Role() != RoleUnknown {
+				err := instance.MarkRoleUnknown(ctx)
+				if err != nil {
+					return err
+				}
+				log.Printf("removed role label from %s: %s", instance.Name(), reason)
+			}
 		} else {
 			if instance.Role() != RoleStandby {
 				err := instance.MarkRoleStandby(ctx)
@@ -66,6 +139,42 @@ func (cmd ApplyPrimaryLabels) Run(ctx context.Context, cfg *Config, cluster Clus
 	return nil
 }
 
+// Returns why each server in |dbstates| other than |primary| should not
+// receive standby traffic: it is unreachable, it is in
+// detected_broken_config, or |primary| reports it more than |maxLag| behind
+// for a database, or cannot tell how far behind it is. A |maxLag| of 0
+// ignores replication lag.
+func UnhealthyStandbys(dbstates []DBState, primary int, maxLag time.Duration) map[int]string {
+	ret := make(map[int]string)
+	for i, state := range dbstates {
+		if i == primary {
+			continue
+		}
+		if state.Err != nil {
+			ret[i] = "it is unreachable"
+			continue
+		}
+		if state.Role == "detected_broken_config" {
+			ret[i] = "it is in detected_broken_config"
+			continue
+		}
+		if maxLag == 0 {
+			continue
+		}
+		for _, row := range StandbyStatusRows(dbstates, primary, i) {
+			if !row.ReplicationLag.Valid {
+				ret[i] = fmt.Sprintf("the replication lag of database %s is unknown", row.Database)
+				break
+			}
+			if row.ReplicationLag.Int64 > maxLag.Milliseconds() {
+				ret[i] = fmt.Sprintf("database %s is %dms behind, more than -mark-unknown-lag %v", row.Database, row.ReplicationLag.Int64, maxLag)
+				break
+			}
+		}
+	}
+	return ret
+}
+
 type GracefulFailover struct{}
 
 func (cmd GracefulFailover) Run(ctx context.Context, cfg *Config, cluster Cluster) error {
@@ -111,6 +220,23 @@ func (cmd GracefulFailover) Run(ctx context.Context, cfg *Config, cluster Cluste
 		return fmt.Errorf("Cannot perform gracefulfailover with min-caughtup-standbys of %d. The version of Dolt on the current primary (%s on pod %s) does not support dolt_cluster_transition_to_standby.", cfg.MinCaughtUpStandbys, dbstates[currentprimary].Version, oldPrimary.Name())
 	}
 
+	target := -1
+	if cfg.FailoverTo != "" {
+		target, err = ResolveInstance(dbstates, cfg.FailoverTo)
+		if err != nil {
+			return fmt.Errorf("cannot perform graceful failover to %s: %w", cfg.FailoverTo, err)
+		}
+		if target == currentprimary {
+			return fmt.Errorf("cannot perform graceful failover to %s: it is the current primary", dbstates[target].Instance.Name())
+		}
+		if dbstates[target].Err != nil {
+			return fmt.Errorf("cannot perform graceful failover to %s: %w", dbstates[target].Instance.Name(), dbstates[target].Err)
+		}
+		if dbstates[target].Role != "standby" {
+			return fmt.Errorf("cannot perform graceful failover to %s: it is in role %s, not standby", dbstates[target].Instance.Name(), dbstates[target].Role)
+		}
+	}
+
 	log.Printf("failing over from %s", oldPrimary.Name())
 
 	for _, state := range dbstates {
@@ -126,6 +252,9 @@ func (cmd GracefulFailover) Run(ctx context.Context, cfg *Config, cluster Cluste
 
 	if cfg.MinCaughtUpStandbys == -1 {
 		nextprimary := (currentprimary + 1) % cluster.NumReplicas()
+		if target != -1 {
+			nextprimary = target
+		}
 		newPrimary = dbstates[nextprimary].Instance
 
 		err = CallAssumeRole(ctx, cfg, oldPrimary, "standby", nextepoch)
@@ -141,7 +270,7 @@ func (cmd GracefulFailover) Run(ctx context.Context, cfg *Config, cluster Cluste
 		}
 		log.Printf("called dolt_assume_cluster_role standby on %s", oldPrimary.Name())
 	} else {
-		nextprimary, err := CallTransitionToStandby(ctx, cfg, oldPrimary, nextepoch, dbstates)
+		nextprimary, caughtUp, err := CallTransitionToStandby(ctx, cfg, oldPrimary, nextepoch, dbstates)
 		if err != nil {
 			log.Printf("failed to transition primary to standby. labeling old primary as primary.")
 			err = oldPrimary.MarkRolePrimary(ctx)
@@ -153,6 +282,29 @@ func (cmd GracefulFailover) Run(ctx context.Context, cfg *Config, cluster Cluste
 			return fmt.Errorf("error calling dolt_cluster_transition_to_standby on %s: %w", oldPrimary.Name(), err)
 		}
 		log.Printf("called dolt_cluster_transition_to_standby on %s", oldPrimary.Name())
+		if target != -1 {
+			if !slices.Contains(caughtUp, target) {
+				// The old primary is already a standby at
+				// nextepoch. It has to become primary again at a
+				// later epoch.
+				log.Printf("%s was not caught up. making old primary, %s, primary again.", dbstates[target].Instance.Name(), oldPrimary.Name())
+				err = CallAssumeRole(ctx, cfg, oldPrimary, "primary", nextepoch+1)
+				if err != nil {
+					log.Printf("ERROR: failed to make old primary primary again.")
+					log.Printf("\t%v", err)
+					log.Printf("The cluster has no primary. You need to run promote.")
+				} else {
+					err = oldPrimary.MarkRolePrimary(ctx)
+					if err != nil {
+						log.Printf("ERROR: failed to label old primary as primary.")
+						log.Printf("\t%v", err)
+						log.Printf("dolt-rw endpoint will be broken. You need to run applyprimarylabels.")
+					}
+				}
+				return fmt.Errorf("cannot perform graceful failover to %s: it was not caught up by dolt_cluster_transition_to_standby on %s", dbstates[target].Instance.Name(), oldPrimary.Name())
+			}
+			nextprimary = target
+		}
 		newPrimary = dbstates[nextprimary].Instance
 	}
 
@@ -185,13 +337,7 @@ func PickNextPrimary(dbstates []DBState) int {
 				firststandby = i
 			}
 
-			var oldestDB time.Time
-			for _, status := range state.Status {
-				if status.LastUpdate.Valid && (oldestDB == (time.Time{}) || oldestDB.After(status.LastUpdate.Time)) {
-					oldestDB = status.LastUpdate.Time
-				}
-			}
-
+			oldestDB := OldestLastUpdate(state)
 			if oldestDB != (time.Time{}) && (updated == (time.Time{}) || updated.Before(oldestDB)) {
 				nextprimary = i
 				updated = oldestDB
@@ -204,24 +350,115 @@ func PickNextPrimary(dbstates []DBState) int {
 	return firststandby
 }
 
+// Returns the oldest last_update across the dolt_cluster_status rows of
+// |state|. For a standby, this is a measure of how recently it has received
+// writes for every database. Returns the zero time if no row has a
+// last_update.
+func OldestLastUpdate(state DBState) time.Time {
+	var oldestDB time.Time
+	for _, status := range state.Status {
+		if status.LastUpdate.Valid && (oldestDB == (time.Time{}) || oldestDB.After(status.LastUpdate.Time)) {
+			oldestDB = status.LastUpdate.Time
+		}
+	}
+	return oldestDB
+}
+
+// Returns the highest epoch reported by any of the servers in |dbstates|, or
+// -1 if |dbstates| is empty.
+func HighestEpoch(dbstates []DBState) int {
+	highestepoch := -1
+	for _, state := range dbstates {
+		if state.Epoch > highestepoch {
+			highestepoch = state.Epoch
+		}
+	}
+	return highestepoch
+}
+
+// Finds the instance in |dbstates| identified by |target|, which is either
+// the ordinal of the instance or its name. A name matches either the full
+// instance name, as in "namespace/pod", or just the part after the last "/",
+// as long as only one instance has that part.
+func ResolveInstance(dbstates []DBState, target string) (int, error) {
+	if i, err := strconv.Atoi(target); err == nil {
+		if i < 0 || i >= len(dbstates) {
+			return -1, fmt.Errorf("ordinal %d is out of range; the cluster has %d replicas", i, len(dbstates))
+		}
+		return i, nil
+	}
+	var matches []int
+	for i, state := range dbstates {
+		name := state.Instance.Name()
+		if name == target {
+			return i, nil
+		}
+		if strings.HasSuffix(name, "/"+target) {
+			matches = append(matches, i)
+		}
+	}
+	if len(matches) > 1 {
+		names := make([]string, len(matches))
+		for j, i := range matches {
+			names[j] = dbstates[i].Instance.Name()
+		}
+		return -1, fmt.Errorf("%s could be any of %s; give the full name of one of them", target, strings.Join(names, ", "))
+	}
+	if len(matches) == 1 {
+		return matches[0], nil
+	}
+	return -1, fmt.Errorf("did not find a pod named %s", target)
+}
+
+// Makes the server at |primary| the primary at |epoch|. Every other
+// reachable server is first made a standby at |epoch|, so that there is
+// never more than one primary. Servers which were not reachable when
+// |dbstates| was loaded are skipped; they will learn of the new epoch when
+// the new primary replicates to them.
+//
+// This does not change any traffic role labels.
+func AssumeRoles(ctx context.Context, cfg *Config, dbstates []DBState, primary, epoch int) error {
+	for i, state := range dbstates {
+		if i == primary {
+			continue
+		}
+		if state.Err != nil {
+			log.Printf("WARNING: not calling dolt_assume_cluster_role standby on unreachable pod %s", state.Instance.Name())
+			continue
+		}
+		err := CallAssumeRole(ctx, cfg, state.Instance, "standby", epoch)
+		if err != nil {
+			return fmt.Errorf("error calling dolt_assume_cluster_role standby on %s: %w", state.Instance.Name(), err)
+		}
+		log.Printf("called dolt_assume_cluster_role standby on %s", state.Instance.Name())
+	}
+
+	newPrimary := dbstates[primary].Instance
+	err := CallAssumeRole(ctx, cfg, newPrimary, "primary", epoch)
+	if err != nil {
+		return fmt.Errorf("error calling dolt_assume_cluster_role primary on %s: %w", newPrimary.Name(), err)
+	}
+	log.Printf("called dolt_assume_cluster_role primary on %s", newPrimary.Name())
+	return nil
+}
+
 type PromoteStandby struct{}
 
 func (cmd PromoteStandby) Run(ctx context.Context, cfg *Config, cluster Cluster) error {
 	// We ignore errors here, since we just want the first reachable standby.
 	dbstates := LoadDBStates(ctx, cfg, cluster)
+	return promoteStandby(ctx, cfg, dbstates)
+}
 
+// Promotes the standby chosen by PickNextPrimary to primary at a new epoch
+// and labels every other pod standby.
+func promoteStandby(ctx context.Context, cfg *Config, dbstates []DBState) error {
 	nextprimary := PickNextPrimary(dbstates)
 	if nextprimary == -1 {
 		return fmt.Errorf("failed to find a reachable standby to promote")
 	}
 
-	highestepoch := -1
-	for _, state := range dbstates {
-		if state.Epoch > highestepoch {
-			highestepoch = state.Epoch
-		}
-	}
-	nextepoch := highestepoch + 1
+	nextepoch := HighestEpoch(dbstates) + 1
 
 	newPrimary := dbstates[nextprimary].Instance
 
@@ -268,6 +505,18 @@ func WaitForDBReady(ctx context.Context, cfg *Config, instance Instance) error {
 	}
 }
 
+// Asks the operator to confirm |prompt| on stdin. Returns true only if they
+// answer yes.
+func Confirm(prompt string) (bool, error) {
+	fmt.Fprintf(os.Stderr, "%s [y/N] ", prompt)
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && (!errors.Is(err, io.EOF) || line == "") {
+		return false, fmt.Errorf("could not read confirmation from stdin: %w", err)
+	}
+	answer := strings.ToLower(strings.TrimSpace(line))
+	return answer == "y" || answer == "yes", nil
+}
+
 type RollingRestart struct {
 }
 
@@ -295,28 +544,10 @@ func (RollingRestart) Run(ctx context.Context, cfg *Config, cluster Cluster) err
 		if i == curprimary {
 			continue
 		}
-		state := dbstates[i]
-		instance := state.Instance
-
-		restartCtx, cancel := context.WithTimeout(ctx, cfg.WaitForReady)
-		defer cancel()
-		err := instance.Restart(restartCtx)
-		if err != nil {
-			return err
-		}
-
-		err = WaitForDBReady(restartCtx, cfg, instance)
+		err := restartStandby(ctx, cfg, dbstates[i].Instance)
 		if err != nil {
 			return err
 		}
-
-		// We need to relabel the pod, since we deleted it.
-		err = instance.MarkRoleStandby(ctx)
-		if err != nil {
-			return err
-		}
-
-		log.Printf("pod is ready %s", instance.Name())
 	}
 
 	// Every standby has been restarted. We failover the primary to the
@@ -337,51 +568,66 @@ func (RollingRestart) Run(ctx context.Context, cfg *Config, cluster Cluster) err
 
 	log.Printf("decided pod %s will be next primary", newPrimary.Name())
 
-	err = oldPrimary.MarkRoleStandby(ctx)
+	err = failoverTo(ctx, cfg, oldPrimary, newPrimary, nextepoch)
 	if err != nil {
 		return err
 	}
-	log.Printf("labeled existing primary, %s, as standby", oldPrimary.Name())
 
-	err = CallAssumeRole(ctx, cfg, oldPrimary, "standby", nextepoch)
+	// Finally restart the old primary.
+	return restartStandby(ctx, cfg, oldPrimary)
+}
+
+// Deletes the pod of |instance|, a standby, waits up to -wait-for-ready for
+// it to come back and accept connections, and labels it standby again.
+func restartStandby(ctx context.Context, cfg *Config, instance Instance) error {
+	restartCtx, cancel := context.WithTimeout(ctx, cfg.WaitForReady)
+	defer cancel()
+	err := instance.Restart(restartCtx)
 	if err != nil {
 		return err
 	}
-	log.Printf("made existing primary, %s, role standby", oldPrimary.Name())
 
-	err = CallAssumeRole(ctx, cfg, newPrimary, "primary", nextepoch)
+	err = WaitForDBReady(restartCtx, cfg, instance)
 	if err != nil {
 		return err
 	}
-	log.Printf("made new primary, %s, role primary", newPrimary.Name())
 
-	err = newPrimary.MarkRolePrimary(ctx)
+	// We need to relabel the pod, since we deleted it.
+	err = instance.MarkRoleStandby(ctx)
 	if err != nil {
 		return err
 	}
-	log.Printf("labeled new primary, %s, role primary", newPrimary.Name())
 
-	// Finally restart the old primary.
+	log.Printf("pod is ready %s", instance.Name())
+	return nil
+}
 
-	restartCtx, cancel := context.WithTimeout(ctx, cfg.WaitForReady)
-	defer cancel()
-	err = oldPrimary.Restart(restartCtx)
+// Makes |oldPrimary| a standby and |newPrimary| the primary at |nextepoch|,
+// moving the primary label along with the role.
+func failoverTo(ctx context.Context, cfg *Config, oldPrimary, newPrimary Instance, nextepoch int) error {
+	err := oldPrimary.MarkRoleStandby(ctx)
 	if err != nil {
 		return err
 	}
+	log.Printf("labeled existing primary, %s, as standby", oldPrimary.Name())
 
-	err = WaitForDBReady(restartCtx, cfg, oldPrimary)
+	err = CallAssumeRole(ctx, cfg, oldPrimary, "standby", nextepoch)
 	if err != nil {
 		return err
 	}
+	log.Printf("made existing primary, %s, role standby", oldPrimary.Name())
 
-	// We need to relabel the pod, since we deleted it.
-	err = oldPrimary.MarkRoleStandby(ctx)
+	err = CallAssumeRole(ctx, cfg, newPrimary, "primary", nextepoch)
 	if err != nil {
 		return err
 	}
+	log.Printf("made new primary, %s, role primary", newPrimary.Name())
 
-	log.Printf("pod is ready %s", oldPrimary.Name())
+	err = newPrimary.MarkRolePrimary(ctx)
+	if err != nil {
+		return err
+	}
+	log.Printf("labeled new primary, %s, role primary", newPrimary.Name())
 
 	return nil
 }
@@ -15,7 +15,9 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"testing"
 	"time"
 
@@ -111,3 +113,134 @@ func TestPickNextPrimary(t *testing.T) {
 		})
 	})
 }
+
+func TestResolveInstance(t *testing.T) {
+	dbstates := []DBState{{
+		Instance: &mockInstance{name: "default/dolt-0"},
+	}, {
+		Instance: &mockInstance{name: "default/dolt-1"},
+	}}
+	t.Run("Ordinal", func(t *testing.T) {
+		res, err := ResolveInstance(dbstates, "1")
+		assert.NoError(t, err)
+		assert.Equal(t, 1, res)
+	})
+	t.Run("OrdinalOutOfRange", func(t *testing.T) {
+		_, err := ResolveInstance(dbstates, "2")
+		assert.Error(t, err)
+	})
+	t.Run("PodName", func(t *testing.T) {
+		res, err := ResolveInstance(dbstates, "dolt-1")
+		assert.NoError(t, err)
+		assert.Equal(t, 1, res)
+	})
+	t.Run("FullName", func(t *testing.T) {
+		res, err := ResolveInstance(dbstates, "default/dolt-0")
+		assert.NoError(t, err)
+		assert.Equal(t, 0, res)
+	})
+	t.Run("NotFound", func(t *testing.T) {
+		_, err := ResolveInstance(dbstates, "dolt-10")
+		assert.Error(t, err)
+	})
+}
+
+func TestApplyLabels(t *testing.T) {
+	dbstates := func() []DBState {
+		return []DBState{{
+			Role:     "primary",
+			Instance: &mockInstance{name: "dolt-0", hostname: "dolt-0.dolt-internal.default"},
+			Status: []StatusRow{{
+				Database:       "mydb",
+				Remote:         "dolt-1",
+				ReplicationLag: sql.NullInt64{Valid: true, Int64: 5000},
+			}, {
+				Database:       "mydb",
+				Remote:         "dolt-2",
+				ReplicationLag: sql.NullInt64{Valid: true, Int64: 0},
+			}},
+			Remotes: []DBRemote{{
+				Database: "mydb",
+				Name:     "dolt-1",
+				URL:      "http://dolt-1.dolt-internal:50051/mydb",
+			}, {
+				Database: "mydb",
+				Name:     "dolt-2",
+				URL:      "http://dolt-2.dolt-internal:50051/mydb",
+			}},
+		}, {
+			Role:     "standby",
+			Instance: &mockInstance{name: "dolt-1", hostname: "dolt-1.dolt-internal.default"},
+		}, {
+			Role:     "standby",
+			Instance: &mockInstance{name: "dolt-2", hostname: "dolt-2.dolt-internal.default"},
+		}, {
+			Instance: &mockInstance{name: "dolt-3", hostname: "dolt-3.dolt-internal.default", role: RoleStandby},
+			Err:      errors.New("connection refused"),
+		}}
+	}
+	roles := func(dbstates []DBState) []Role {
+		var ret []Role
+		for _, state := range dbstates {
+			ret = append(ret, state.Instance.Role())
+		}
+		return ret
+	}
+	t.Run("Default", func(t *testing.T) {
+		states := dbstates()
+		err := ApplyLabels(context.Background(), &Config{}, states)
+		assert.NoError(t, err)
+		assert.Equal(t, []Role{RolePrimary, RoleStandby, RoleStandby, RoleStandby}, roles(states))
+	})
+	t.Run("MarkUnknown", func(t *testing.T) {
+		states := dbstates()
+		err := ApplyLabels(context.Background(), &Config{MarkUnknown: true}, states)
+		assert.NoError(t, err)
+		assert.Equal(t, []Role{RolePrimary, RoleStandby, RoleStandby, RoleUnknown}, roles(states))
+	})
+	t.Run("MarkUnknownLag", func(t *testing.T) {
+		states := dbstates()
+		err := ApplyLabels(context.Background(), &Config{MarkUnknown: true, MarkUnknownLag: time.Second}, states)
+		assert.NoError(t, err)
+		assert.Equal(t, []Role{RolePrimary, RoleUnknown, RoleStandby, RoleUnknown}, roles(states))
+	})
+	t.Run("Restores", func(t *testing.T) {
+		states := dbstates()
+		states[3].Err = nil
+		states[3].Role = "standby"
+		states[3].Instance.(*mockInstance).role = RoleUnknown
+		err := ApplyLabels(context.Background(), &Config{MarkUnknown: true}, states)
+		assert.NoError(t, err)
+		assert.Equal(t, []Role{RolePrimary, RoleStandby, RoleStandby, RoleStandby}, roles(states))
+	})
+}
+
+func TestUnhealthyStandbys(t *testing.T) {
+	res := UnhealthyStandbys([]DBState{
+		{Role: "primary", Instance: &mockInstance{name: "dolt-0"}},
+		{Role: "detected_broken_config", Instance: &mockInstance{name: "dolt-1"}},
+		{Role: "standby", Instance: &mockInstance{name: "dolt-2"}},
+	}, 0, 0)
+	assert.Equal(t, map[int]string{1: "it is in detected_broken_config"}, res)
+
+	t.Run("UnknownLag", func(t *testing.T) {
+		dbstates := []DBState{{
+			Role:     "primary",
+			Instance: &mockInstance{name: "dolt-0", hostname: "dolt-0.dolt-internal.default"},
+			Status: []StatusRow{{
+				Database: "mydb",
+				Remote:   "dolt-1",
+			}},
+			Remotes: []DBRemote{{
+				Database: "mydb",
+				Name:     "dolt-1",
+				URL:      "http://dolt-1.dolt-internal:50051/mydb",
+			}},
+		}, {
+			Role:     "standby",
+			Instance: &mockInstance{name: "dolt-1", hostname: "dolt-1.dolt-internal.default"},
+		}}
+		assert.Empty(t, UnhealthyStandbys(dbstates, 0, 0))
+		assert.Equal(t, map[int]string{1: "the replication lag of database mydb is unknown"}, UnhealthyStandbys(dbstates, 0, time.Second))
+	})
+}
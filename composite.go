@@ -0,0 +1,169 @@
+// Copyright 2023 DoltHub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// A StatefulSet which, along with the one named on the command line, makes
+// up a cluster. If Context is empty, it is in the same Kubernetes cluster.
+type ClusterMember struct {
+	Context     string
+	Namespace   string
+	StatefulSet string
+}
+
+// Parses a -member of the form [context:]namespace/statefulset. Namespaces
+// and StatefulSet names cannot contain ':' or '/', but context names can.
+func ParseClusterMember(s string) (ClusterMember, error) {
+	var m ClusterMember
+	slash := strings.LastIndexByte(s, '/')
+	if slash == -1 {
+		return m, fmt.Errorf("cluster member %s is not of the form [context:]namespace/statefulset", s)
+	}
+	m.StatefulSet = s[slash+1:]
+	m.Namespace = s[:slash]
+	if colon := strings.LastIndexByte(m.Namespace, ':'); colon != -1 {
+		m.Context = m.Namespace[:colon]
+		m.Namespace = m.Namespace[colon+1:]
+	}
+	if m.Namespace == "" || m.StatefulSet == "" {
+		return m, fmt.Errorf("cluster member %s is not of the form [context:]namespace/statefulset", s)
+	}
+	return m, nil
+}
+
+func (m ClusterMember) String() string {
+	if m.Context != "" {
+		return m.Context + ":" + m.Namespace + "/" + m.StatefulSet
+	}
+	return m.Namespace + "/" + m.StatefulSet
+}
+
+// A Cluster made up of the instances of several Clusters, for example a
+// StatefulSet and its disaster recovery standbys in another StatefulSet,
+// namespace or Kubernetes cluster. The instances of the first member come
+// first, then those of the second, and so on.
+//
+// Instances are those of their members, so their roles are marked in
+// their own member's service registry. The cluster is a WatchableCluster,
+// refreshing and watching those members which are; it is never an
+// UpgradableCluster or a ScalableCluster, as those change a single
+// deployment.
+type compositeCluster struct {
+	members []Cluster
+}
+
+func NewCompositeCluster(members []Cluster) Cluster {
+	return &compositeCluster{members: members}
+}
+
+func (cc *compositeCluster) Name() string {
+	names := make([]string, len(cc.members))
+	for i, m := range cc.members {
+		names[i] = m.Name()
+	}
+	return strings.Join(names, "+")
+}
+
+func (cc *compositeCluster) NumReplicas() int {
+	n := 0
+	for _, m := range cc.members {
+		n += m.NumReplicas()
+	}
+	return n
+}
+
+func (cc *compositeCluster) Instance(i int) Instance {
+	for _, m := range cc.members {
+		if i < m.NumReplicas() {
+			return m.Instance(i)
+		}
+		i -= m.NumReplicas()
+	}
+	return nil
+}
+
+// A Cluster which can load what Refresh would without applying it, so that
+// a compositeCluster can refresh all of its members or none of them.
+type stagedRefresher interface {
+	// Loads the deployment state and returns a function which applies
+	// it, like Refresh does.
+	stageRefresh(context.Context) (func(), error)
+}
+
+// Refreshes every member which is a WatchableCluster. The members are all
+// loaded before any of them changes, so that if one fails, the cluster is
+// left as it was. Only members which cannot stage a refresh are refreshed
+// in place, after every other member loaded successfully, and those may be
+// left refreshed if a later one of them fails.
+func (cc *compositeCluster) Refresh(ctx context.Context) error {
+	var applies []func()
+	var unstaged []WatchableCluster
+	for _, m := range cc.members {
+		if s, ok := m.(stagedRefresher); ok {
+			apply, err := s.stageRefresh(ctx)
+			if err != nil {
+				return err
+			}
+			applies = append(applies, apply)
+		} else if w, ok := m.(WatchableCluster); ok {
+			unstaged = append(unstaged, w)
+		}
+	}
+	for _, w := range unstaged {
+		err := w.Refresh(ctx)
+		if err != nil {
+			return err
+		}
+	}
+	for _, apply := range applies {
+		apply()
+	}
+	return nil
+}
+
+// Receives a value whenever any of the members which are WatchableClusters
+// changes.
+func (cc *compositeCluster) Watch(ctx context.Context) (<-chan struct{}, error) {
+	ch := make(chan struct{}, 1)
+	for _, m := range cc.members {
+		w, ok := m.(WatchableCluster)
+		if !ok {
+			continue
+		}
+		mch, err := w.Watch(ctx)
+		if err != nil {
+			return nil, err
+		}
+		go func() {
+			for {
+				select {
+				case <-mch:
+					select {
+					case ch <- struct{}{}:
+					default:
+					}
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	return ch, nil
+}
@@ -0,0 +1,163 @@
+// Copyright 2023 DoltHub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// A WatchableCluster of mockInstances.
+type instancesCluster struct {
+	name       string
+	instances  []Instance
+	refreshes  int
+	refreshErr error
+	changes    chan struct{}
+}
+
+func (c *instancesCluster) Name() string {
+	return c.name
+}
+
+func (c *instancesCluster) NumReplicas() int {
+	return len(c.instances)
+}
+
+func (c *instancesCluster) Instance(i int) Instance {
+	return c.instances[i]
+}
+
+func (c *instancesCluster) Refresh(ctx context.Context) error {
+	apply, err := c.stageRefresh(ctx)
+	if err != nil {
+		return err
+	}
+	apply()
+	return nil
+}
+
+func (c *instancesCluster) stageRefresh(context.Context) (func(), error) {
+	if c.refreshErr != nil {
+		return nil, c.refreshErr
+	}
+	return func() { c.refreshes++ }, nil
+}
+
+func (c *instancesCluster) Watch(context.Context) (<-chan struct{}, error) {
+	return c.changes, nil
+}
+
+func TestParseClusterMember(t *testing.T) {
+	for _, test := range []struct {
+		in   string
+		want ClusterMember
+	}{
+		{"dolt/dolt-dr", ClusterMember{Namespace: "dolt", StatefulSet: "dolt-dr"}},
+		{"kind-dr:dolt/dolt", ClusterMember{Context: "kind-dr", Namespace: "dolt", StatefulSet: "dolt"}},
+		{"arn:aws:eks:us-west-2:012345678901:cluster/dr:dolt/dolt", ClusterMember{Context: "arn:aws:eks:us-west-2:012345678901:cluster/dr", Namespace: "dolt", StatefulSet: "dolt"}},
+	} {
+		got, err := ParseClusterMember(test.in)
+		if assert.NoError(t, err, test.in) {
+			assert.Equal(t, test.want, got)
+			assert.Equal(t, test.in, got.String())
+		}
+	}
+	for _, in := range []string{"dolt", "dolt/", "/dolt", "kind-dr:/dolt"} {
+		_, err := ParseClusterMember(in)
+		assert.Error(t, err, in)
+	}
+}
+
+func TestCompositeCluster(t *testing.T) {
+	primary := &instancesCluster{
+		name: "dolt/dolt",
+		instances: []Instance{
+			&mockInstance{name: "dolt/dolt-0", role: RolePrimary},
+			&mockInstance{name: "dolt/dolt-1", role: RoleStandby},
+		},
+		changes: make(chan struct{}),
+	}
+	dr := &instancesCluster{
+		name: "dr:dolt/dolt",
+		instances: []Instance{
+			&mockInstance{name: "dr:dolt/dolt-0", role: RoleStandby},
+		},
+		changes: make(chan struct{}),
+	}
+	cluster := NewCompositeCluster([]Cluster{primary, dr})
+
+	assert.Equal(t, "dolt/dolt+dr:dolt/dolt", cluster.Name())
+	assert.Equal(t, 3, cluster.NumReplicas())
+	assert.Equal(t, "dolt/dolt-0", cluster.Instance(0).Name())
+	assert.Equal(t, "dolt/dolt-1", cluster.Instance(1).Name())
+	assert.Equal(t, "dr:dolt/dolt-0", cluster.Instance(2).Name())
+	assert.Nil(t, cluster.Instance(3))
+
+	// Marking a role goes to the member's own instance.
+	assert.NoError(t, cluster.Instance(2).MarkRolePrimary(context.Background()))
+	assert.Equal(t, RolePrimary, dr.instances[0].Role())
+
+	_, ok := cluster.(UpgradableCluster)
+	assert.False(t, ok)
+	_, ok = cluster.(ScalableCluster)
+	assert.False(t, ok)
+
+	watchable, ok := cluster.(WatchableCluster)
+	if !assert.True(t, ok) {
+		return
+	}
+	assert.NoError(t, watchable.Refresh(context.Background()))
+	assert.Equal(t, 1, primary.refreshes)
+	assert.Equal(t, 1, dr.refreshes)
+
+	dr.refreshErr = errors.New("dr is unreachable")
+	assert.ErrorIs(t, watchable.Refresh(context.Background()), dr.refreshErr)
+	assert.Equal(t, 1, primary.refreshes, "the primary member should be left as it was")
+	assert.Equal(t, 1, dr.refreshes)
+	dr.refreshErr = nil
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes, err := watchable.Watch(ctx)
+	if !assert.NoError(t, err) {
+		return
+	}
+	dr.changes <- struct{}{}
+	select {
+	case <-changes:
+	case <-time.After(time.Second):
+		t.Fatal("expected a change from the dr member")
+	}
+
+	t.Run("ResolveInstance", func(t *testing.T) {
+		dbstates := make([]DBState, cluster.NumReplicas())
+		for i := range dbstates {
+			dbstates[i].Instance = cluster.Instance(i)
+		}
+		i, err := ResolveInstance(dbstates, "dr:dolt/dolt-0")
+		assert.NoError(t, err)
+		assert.Equal(t, 2, i)
+		i, err = ResolveInstance(dbstates, "dolt/dolt-0")
+		assert.NoError(t, err)
+		assert.Equal(t, 0, i)
+		_, err = ResolveInstance(dbstates, "dolt-0")
+		assert.EqualError(t, err, "dolt-0 could be any of dolt/dolt-0, dr:dolt/dolt-0; give the full name of one of them")
+	})
+}
@@ -24,21 +24,73 @@ import (
 	"os"
 	"strconv"
 	"time"
+
+	"k8s.io/apimachinery/pkg/labels"
 )
 
 const SubcommandsUsage = `
 SUBCOMMANDS
 
-  doltclusterctl applyprimarylabels statefulset_name - sets/unsets the primary labels on the pods in the StatefulSet with metadata.name: statefulset-name; labels the other pods standby.
-  doltclusterctl gracefulfailover statefulset_name - takes the current primary, marks it as a standby, and marks the next replica in the set as the primary.
+  doltclusterctl applyprimarylabels statefulset_name - sets/unsets the primary labels on the pods in the StatefulSet with metadata.name: statefulset-name; labels the other pods standby. With -mark-unknown, removes the role label from pods which are unreachable, in detected_broken_config, or more than -mark-unknown-lag behind.
+  doltclusterctl autofailover statefulset_name - runs until it receives SIGTERM, health checking the primary every -interval; after -failure-threshold consecutive failed checks spanning -failure-window, promotes the best reachable standby.
+  doltclusterctl backup statefulset_name - backs up every database from the most caught up standby to its name under -backup-url with dolt_backup('sync-url', ...) and prints a manifest of the source pod and the branch heads backed up; fails if the standby is more than -backup-max-lag behind.
+  doltclusterctl bootstrap statefulset_name [pod_or_ordinal] - for a freshly deployed cluster, checks that no pod is above -bootstrap-epoch and that every pod has the same databases, makes the given pod (by default, ordinal 0) primary and every other pod standby at the next epoch, applies the labels, and checks that a write to the primary replicates to every standby.
+  doltclusterctl check statefulset_name - checks the health of the cluster like a monitoring plugin, printing a one-line summary with perf data and exiting 0 (OK), 1 (WARNING), 2 (CRITICAL) or 3 (UNKNOWN); see -max-lag, -require-standbys and -fail-on-current-error.
+  doltclusterctl drain statefulset_name - moves the pods of the stateful set off of the node given with -node; fails over first if the primary is on it, to the standby given with -to or a standby on another node, then removes the role labels of the pods on the node and evicts them one at a time, waiting for each to be rescheduled and ready.
+  doltclusterctl gc statefulset_name - runs dolt_gc on each standby in turn, waiting for it to be healthy and caught up again, then gracefully fails over the primary and runs dolt_gc on the old primary; reports the disk space reclaimed on each pod.
+  doltclusterctl gracefulfailover statefulset_name - takes the current primary, marks it as a standby, and marks the next replica in the set, or the replica given with -to, as the primary.
+  doltclusterctl manifests statefulset_name - writes the Kubernetes objects for a complete cluster of -replicas pods running -image, with the given name in the namespace given with -n, to stdout as YAML for kubectl apply; does not connect to Kubernetes.
+  doltclusterctl promote statefulset_name pod_or_ordinal - makes the given pod the new primary at a fresh epoch and every other reachable pod a standby; refuses to promote a standby which is not caught up unless -force is given.
   doltclusterctl promotestandby statefulset_name - takes the first reachable standby and makes it the new primary.
+  doltclusterctl reconcile statefulset_name - runs until it receives SIGTERM, applying the primary labels every -interval and whenever the stateful set or its pods change.
+  doltclusterctl reseed statefulset_name pod_or_ordinal - after confirmation (or with -yes), deletes the given standby along with its PersistentVolumeClaims, waits for it to be recreated and ready, and waits for the primary to report it caught up to within -max-lag for every database.
+  doltclusterctl resolvebrokenconfig statefulset_name - for a cluster with pods in detected_broken_config, shows the epoch, replication status and branch heads of every pod, proposes the pod which should become primary, and after confirmation (or with -yes) makes it primary and every other pod standby at a new epoch.
+  doltclusterctl restart statefulset_name pod_or_ordinal - deletes the given pod and waits for it to be recreated and ready, then labels it standby; if it is the primary, first performs a graceful failover, honoring -min-caughtup-standbys and -to.
   doltclusterctl rollingrestart statefulset_name - deletes all pods in the stateful set, one at a time, waiting for the deleted pods to be recreated and ready before moving on; gracefully fails over the primary before deleting it.
+  doltclusterctl scale statefulset_name replicas - changes the number of pods in the stateful set; refuses unless the standby remotes of the remaining pods match the new membership. Before scaling down, fails the primary over to a remaining pod, honoring -to; after scaling up, waits for the new pods to be caught up to within -max-lag.
+  doltclusterctl status statefulset_name - prints the role, epoch, version and replication status of every pod in the stateful set, along with its dolthub.com/cluster_role label; exits non-zero if the cluster is unhealthy.
+  doltclusterctl upgrade statefulset_name - changes the dolt image of the stateful set to -image and restarts the standbys onto it, then fails over to a standby on the new version and restarts the old primary; stops if a restarted pod reports an unexpected version or a version which drops a capability. Requires spec.updateStrategy.type: OnDelete.
+  doltclusterctl verify statefulset_name - compares the head and working set of every branch of every database on each standby against the primary and reports those which are missing, behind or diverged; with -wait-for-convergence, first waits that long for standbys to catch up.
+  doltclusterctl waitforcaughtup statefulset_name - blocks until the primary reports every standby caught up to within -max-lag, with no replication errors, for every database; exits non-zero with a report of every database if -timeout expires first.
 `
 
 type Config struct {
 	// The kubernetes namespace of the statefulset.
 	Namespace string
 
+	// The kubeconfig file and context to load, from outside the cluster.
+	// If both are empty, we use the in-cluster configuration when we are
+	// running in a pod, and the standard loading rules otherwise.
+	Kubeconfig string
+	Context    string
+
+	// How to reach the sql-servers: ConnectViaDirect, at their hostnames,
+	// or ConnectViaPortForward, through port-forwards opened through the
+	// Kubernetes API server.
+	ConnectVia string
+
+	// The label selector of the pods of the cluster, and an annotation
+	// by which they are ordered. By default, the replicas of the
+	// StatefulSet, ordered by the ordinals in their names.
+	Selector          string
+	OrdinalAnnotation string
+
+	// The StatefulSets which, along with StatefulSetName, make up the
+	// cluster.
+	Members []ClusterMember
+
+	// Run the command in a pod in the namespace of the StatefulSet, with
+	// RemoteImage, as RemoteServiceAccount, instead of running it here.
+	// RemoteTLSSecret names a Secret with the ca.crt the remote
+	// doltclusterctl verifies the servers with, and
+	// RemoteCredentialsSecret one with the username and password it
+	// connects to them with.
+	Remote                  bool
+	RemoteImage             string
+	RemoteServiceAccount    string
+	RemoteTLSSecret         string
+	RemoteCredentialsSecret string
+
 	// A *tls.Config which could have been built in argument parsing.
 	TLSConfig *tls.Config
 
@@ -48,8 +100,11 @@ type Config struct {
 	// unverified.
 	TLSInsecure bool
 
-	// The timeout for the entire command run.
+	// The timeout for the entire command run. For long-running commands,
+	// the timeout for each pass they make over the cluster.
 	Timeout time.Duration
+	// How often long-running commands make a pass over the cluster.
+	Interval time.Duration
 	// A timeout for how long to wait for each individual restarted pod to
 	// come back and be ready.
 	WaitForReady time.Duration
@@ -61,12 +116,116 @@ type Config struct {
 	// The number of standbys which must be caught up, when running a
 	// graceful failover, in order to proceed.
 	MinCaughtUpStandbys int
+
+	// The ordinal or name of the standby which a graceful failover should
+	// make the new primary. If empty, gracefulfailover chooses.
+	FailoverTo string
+
+	// Proceed with operations which would otherwise be refused as
+	// unsafe, such as promoting a standby which is not caught up.
+	Force bool
+
+	// Do not ask for confirmation before operations which ask for it.
+	Yes bool
+
+	// The number of consecutive failed health checks of the primary, and
+	// the minimum time they must span, before autofailover fails over.
+	FailureThreshold int
+	FailureWindow    time.Duration
+	// The number of standbys which must be reachable for autofailover to
+	// fail over.
+	MinReachableStandbys int
+	// The minimum time between two failovers made by autofailover.
+	FailoverCooldown time.Duration
+
+	// The image upgrade changes the StatefulSet to, and the image of the
+	// cluster manifests generates.
+	Image string
+	// The dolt_version() every pod must report after upgrade restarts it.
+	// If empty, the version the first restarted pod reports.
+	ExpectVersion string
+
+	// The replication lag at or below which waitforcaughtup, gc, reseed
+	// and scale consider a standby caught up, and above which check is
+	// WARNING.
+	MaxLag time.Duration
+
+	// The URL under which backup syncs a backup of each database.
+	BackupURL string
+	// The replication lag past which backup considers its source standby
+	// too far behind.
+	BackupMaxLag time.Duration
+
+	// The node drain moves pods off of.
+	Node string
+
+	// Remove the role label from standbys which are unreachable, in
+	// detected_broken_config, or more than MarkUnknownLag or an unknown
+	// amount behind, when applying labels. A MarkUnknownLag of 0 ignores
+	// replication lag.
+	MarkUnknown    bool
+	MarkUnknownLag time.Duration
+
+	// The number of reachable standbys below which check is CRITICAL, and
+	// whether a replication error makes it CRITICAL rather than WARNING.
+	RequireStandbys    int
+	FailOnCurrentError bool
+
+	// The bootstrap_epoch in the config.yaml of the servers of a freshly
+	// deployed cluster. bootstrap assigns roles at the epoch after it.
+	BootstrapEpoch int
+
+	// The number of pods, the volume size and the sql-server TLS settings
+	// of the cluster manifests generates. ServerTLSSecret names a
+	// kubernetes.io/tls Secret; if it is empty, the sql-servers do not
+	// serve TLS.
+	Replicas         int
+	StorageSize      string
+	ServerTLSSecret  string
+	ServerRequireTLS bool
+
+	// Measure how long writes are unavailable while the command runs, by
+	// writing heartbeats through PrimaryService, by default the -rw
+	// service of the StatefulSet, and reading them back from each pod.
+	MeasureDowntime bool
+	PrimaryService  string
+
+	// How long verify waits for standbys which are behind the primary to
+	// catch up before reporting them.
+	WaitForConvergence time.Duration
 }
 
 func (c *Config) InitFlagSet(set *flag.FlagSet) {
 	set.StringVar(&c.Namespace, "n", "default", "namespace of the stateful set to operate on")
+	set.StringVar(&c.Kubeconfig, "kubeconfig", "", "the kubeconfig file to use to talk to the Kubernetes API server; by default, the in-cluster configuration when running in a pod, and otherwise $KUBECONFIG or ~/.kube/config")
+	set.StringVar(&c.Context, "context", "", "the context in the kubeconfig to use; by default, its current context")
+	set.StringVar(&c.Selector, "selector", "", "the label selector of the pods of the cluster, which can include pods outside of the stateful set; cannot be used with -member; by default, the selector of the stateful set, in which case every one of its replicas has to exist")
+	set.StringVar(&c.OrdinalAnnotation, "ordinal-annotation", "", "an annotation with an integer value by which the pods of the cluster are ordered; cannot be used with -member; by default, they are ordered by the ordinal at the end of their names")
+	set.Func("member", "a StatefulSet, as [context:]namespace/statefulset, which is part of the cluster along with the one named on the command line, for example disaster recovery standbys in another namespace or Kubernetes cluster; can be given more than once", func(s string) error {
+		m, err := ParseClusterMember(s)
+		if err != nil {
+			return err
+		}
+		c.Members = append(c.Members, m)
+		return nil
+	})
+	set.BoolVar(&c.Remote, "remote", false, "if true, run the command in a pod in the namespace of the stateful set instead of here, stream its logs, and exit with its exit code; requires -remote-image")
+	set.StringVar(&c.RemoteImage, "remote-image", "", "the doltclusterctl image -remote runs the command with")
+	set.StringVar(&c.RemoteServiceAccount, "remote-service-account", "doltclusterctl", "the service account -remote runs the command as")
+	set.StringVar(&c.RemoteTLSSecret, "remote-tls-secret", "", "a Secret with a ca.crt which -remote mounts in its pod and uses as -tls-ca, to verify the servers' certificates")
+	set.StringVar(&c.RemoteCredentialsSecret, "remote-credentials-secret", "", "a Secret with a password, and optionally a username, which -remote connects to the servers with, like DOLT_PASSWORD and DOLT_USERNAME")
+	set.StringVar(&c.ConnectVia, "connect-via", ConnectViaDirect, "how to reach the sql-servers: "+ConnectViaDirect+", at the DNS names of the pods in the headless service, which only resolve inside the cluster, or "+ConnectViaPortForward+", through a port-forward to each pod opened through the Kubernetes API server, for running from outside the cluster")
 
 	set.IntVar(&c.MinCaughtUpStandbys, "min-caughtup-standbys", -1, "the number of standby servers which must be caughtup on a graceful failover in order to succeed")
+	set.StringVar(&c.FailoverTo, "to", "", "the ordinal or name of the standby pod which gracefulfailover should make the new primary; by default, the next pod after the current primary, or with -min-caughtup-standbys, the most caught up standby")
+	set.BoolVar(&c.Yes, "yes", false, "if true, do not ask for confirmation on stdin before operations which ask for it, such as resolvebrokenconfig and reseed")
+	set.IntVar(&c.FailureThreshold, "failure-threshold", 3, "the number of consecutive failed health checks of the primary after which autofailover fails over")
+	set.DurationVar(&c.FailureWindow, "failure-window", time.Second*30, "the minimum time between the first and the last of the failed health checks after which autofailover fails over")
+	set.IntVar(&c.MinReachableStandbys, "min-reachable-standbys", 1, "the number of standbys which must be reachable for autofailover to fail over")
+	set.DurationVar(&c.FailoverCooldown, "failover-cooldown", time.Minute*5, "the minimum time between two failovers made by autofailover")
+	set.StringVar(&c.Image, "image", "", "the image upgrade changes the dolt container of the stateful set to; for manifests, the image of the generated stateful set, by default "+ManifestsDefaultImage)
+	set.StringVar(&c.ExpectVersion, "expect-version", "", "the dolt_version() every pod must report after upgrade restarts it; by default, the version the first restarted pod reports")
+	set.BoolVar(&c.Force, "force", false, "if true, proceed with operations which would otherwise be refused as unsafe, such as promoting a standby which is not caught up")
 
 	set.Func("tls-server-name", "if provided, enables manadatory verified TLS mode and overrides the server name to verify as the CN or SAN of the leaf certificate (and present in SNI)", func(sn string) error {
 		if c.TLSInsecure {
@@ -99,12 +258,29 @@ func (c *Config) InitFlagSet(set *flag.FlagSet) {
 	set.Var((*tlsVerifiedFlagValue)(c), "tls", "if provided, enables manadatory verified TLS mode")
 	set.Var((*tlsInsecureFlagValue)(c), "tls-insecure", "if true, enables tls mode for communicating with the server, but does not verify the server's certificate")
 
-	set.DurationVar(&c.Timeout, "timeout", time.Second*30, "the number of seconds the entire command has to run before it timeouts and exits non-zero")
+	set.DurationVar(&c.Timeout, "timeout", time.Second*30, "the number of seconds the entire command has to run before it timeouts and exits non-zero; for long-running commands like reconcile, the timeout for each pass over the cluster")
+	set.DurationVar(&c.Interval, "interval", time.Second*10, "how often long-running commands like reconcile make a pass over the cluster")
+	set.StringVar(&c.BackupURL, "backup-url", "", "the URL under which backup syncs a backup of each database, as in file:///backups or aws://[table:bucket]/backups; each database is backed up to its name under it")
+	set.DurationVar(&c.BackupMaxLag, "backup-max-lag", time.Minute, "the replication lag past which backup fails, because its source standby is too far behind")
+	set.StringVar(&c.Node, "node", "", "the name of the node drain moves pods off of")
+	set.DurationVar(&c.MaxLag, "max-lag", 0, "the replication lag at or below which waitforcaughtup, gc, reseed and scale consider a standby caught up, and above which check is WARNING")
+	set.BoolVar(&c.MarkUnknown, "mark-unknown", false, "if true, applyprimarylabels and reconcile remove the dolthub.com/cluster_role label from pods which are unreachable, in detected_broken_config, or more than -mark-unknown-lag behind, and restore it once they recover")
+	set.DurationVar(&c.MarkUnknownLag, "mark-unknown-lag", 0, "with -mark-unknown, the replication lag past which a standby has its label removed; 0 ignores replication lag")
+	set.IntVar(&c.RequireStandbys, "require-standbys", 1, "the number of pods which must be reachable in role standby for check not to be CRITICAL")
+	set.BoolVar(&c.FailOnCurrentError, "fail-on-current-error", false, "if true, check is CRITICAL, rather than WARNING, when the primary reports a replication error for a standby")
+	set.IntVar(&c.BootstrapEpoch, "bootstrap-epoch", 1, "the bootstrap_epoch in the config.yaml of the servers of the cluster, which dolt defaults to 1; bootstrap refuses to run against a cluster with a pod above it, and assigns roles at the epoch after it, since dolt does not change the role of a server without raising its epoch")
+	set.IntVar(&c.Replicas, "replicas", 2, "the number of pods in the stateful set manifests generates")
+	set.StringVar(&c.StorageSize, "storage-size", "10Gi", "the size of the PersistentVolumeClaim of each pod in the stateful set manifests generates")
+	set.StringVar(&c.ServerTLSSecret, "server-tls-secret", "", "the kubernetes.io/tls Secret with the certificate the sql-servers manifests generates serve TLS with; if empty, they do not serve TLS")
+	set.BoolVar(&c.ServerRequireTLS, "server-require-tls", false, "if true, the sql-servers manifests generates require TLS connections; requires -server-tls-secret")
+	set.BoolVar(&c.MeasureDowntime, "measure-downtime", false, "if true, write heartbeats through -primary-service and read them back from every pod for the whole run, and report how long writes were unavailable, the maximum replication delay and any lost heartbeats; creates and drops the database "+HeartbeatDatabase)
+	set.StringVar(&c.PrimaryService, "primary-service", "", "the host of the service which routes writes to the primary, for -measure-downtime; by default, statefulset_name-rw.namespace")
+	set.DurationVar(&c.WaitForConvergence, "wait-for-convergence", 0, "how long verify waits for standbys which are behind the primary to catch up before reporting them; must be less than -timeout")
 	set.DurationVar(&c.WaitForReady, "wait-for-ready", time.Second*120, "the number of seconds to wait for a single pod to become ready when performing a rollingrestart until we consider the operation failed")
 
 	set.Usage = func() {
 		fmt.Fprintf(set.Output(), "Usage of %s:\n\n", set.Name())
-		fmt.Fprintf(set.Output(), "  %s [COMMON OPTIONS...] subcommand statefulset_name\n\nCOMMON OPTIONS\n\n", set.Name())
+		fmt.Fprintf(set.Output(), "  %s [COMMON OPTIONS...] subcommand statefulset_name [ARGS...]\n\nCOMMON OPTIONS\n\n", set.Name())
 		flag.PrintDefaults()
 		fmt.Fprint(set.Output(), SubcommandsUsage)
 	}
@@ -117,11 +293,20 @@ func (c *Config) Parse(set *flag.FlagSet, args []string) error {
 		return err
 	}
 
+	// Nagios reads exit code 2 as CRITICAL, so check reports usage errors
+	// as UNKNOWN instead.
+	check := set.Arg(0) == "check"
 	errF := func(err error) error {
 		switch set.ErrorHandling() {
 		case flag.ContinueOnError:
+			if check {
+				return &ExitError{Code: CheckUnknown, Err: err}
+			}
 			return err
 		case flag.ExitOnError:
+			if check {
+				os.Exit(Check{}.ReportFailure(err))
+			}
 			os.Exit(2)
 		case flag.PanicOnError:
 			panic(err)
@@ -129,29 +314,151 @@ func (c *Config) Parse(set *flag.FlagSet, args []string) error {
 		panic("unexpected ErrorHandling value")
 	}
 
-	if set.NArg() != 2 {
-		str := fmt.Sprintf("must provide subcommand and the name of the StatefulSet")
+	usageErr := func(str string) error {
 		fmt.Fprintln(set.Output(), str)
 		set.Usage()
 		return errF(errors.New(str))
 	}
 
+	if set.NArg() < 2 {
+		return usageErr("must provide subcommand and the name of the StatefulSet")
+	}
+
 	c.CommandStr = set.Arg(0)
 	c.StatefulSetName = set.Arg(1)
 
+	// The number of arguments the subcommand takes after the StatefulSet name.
+	nargs := 0
+
 	if c.CommandStr == "applyprimarylabels" {
 		c.Command = ApplyPrimaryLabels{}
+	} else if c.CommandStr == "autofailover" {
+		c.Command = AutoFailover{}
+	} else if c.CommandStr == "backup" {
+		if c.BackupURL == "" {
+			return usageErr("subcommand backup requires -backup-url")
+		}
+		c.Command = Backup{}
+	} else if c.CommandStr == "bootstrap" {
+		target := "0"
+		if set.NArg() > 2 {
+			nargs = 1
+			target = set.Arg(2)
+		}
+		c.Command = Bootstrap{Target: target}
+	} else if c.CommandStr == "check" {
+		c.Command = Check{}
+	} else if c.CommandStr == "drain" {
+		if c.Node == "" {
+			return usageErr("subcommand drain requires -node")
+		}
+		c.Command = Drain{}
+	} else if c.CommandStr == "gc" {
+		c.Command = GC{}
 	} else if c.CommandStr == "gracefulfailover" {
 		c.Command = GracefulFailover{}
+	} else if c.CommandStr == "manifests" {
+		if c.ServerRequireTLS && c.ServerTLSSecret == "" {
+			return usageErr("-server-require-tls requires -server-tls-secret")
+		}
+		c.Command = Manifests{}
+	} else if c.CommandStr == "promote" {
+		nargs = 1
+		c.Command = Promote{Target: set.Arg(2)}
 	} else if c.CommandStr == "promotestandby" {
 		c.Command = PromoteStandby{}
+	} else if c.CommandStr == "reconcile" {
+		c.Command = Reconcile{}
+	} else if c.CommandStr == "reseed" {
+		nargs = 1
+		c.Command = Reseed{Target: set.Arg(2)}
+	} else if c.CommandStr == "resolvebrokenconfig" {
+		c.Command = ResolveBrokenConfig{}
+	} else if c.CommandStr == "restart" {
+		nargs = 1
+		c.Command = Restart{Target: set.Arg(2)}
 	} else if c.CommandStr == "rollingrestart" {
 		c.Command = RollingRestart{}
+	} else if c.CommandStr == "scale" {
+		nargs = 1
+		if set.NArg() == 3 {
+			replicas, err := strconv.Atoi(set.Arg(2))
+			if err != nil || replicas < 2 {
+				return usageErr(fmt.Sprintf("subcommand scale takes a number of replicas of at least 2, not %s", set.Arg(2)))
+			}
+			c.Command = Scale{Replicas: replicas}
+		}
+	} else if c.CommandStr == "status" {
+		c.Command = Status{}
+	} else if c.CommandStr == "upgrade" {
+		if c.Image == "" {
+			return usageErr("subcommand upgrade requires -image")
+		}
+		c.Command = Upgrade{}
+	} else if c.CommandStr == "verify" {
+		c.Command = Verify{}
+	} else if c.CommandStr == "waitforcaughtup" {
+		c.Command = WaitForCaughtUp{}
 	} else {
-		str := fmt.Sprintf("did not find subcommand %s", c.CommandStr)
-		fmt.Fprintln(set.Output(), str)
-		set.Usage()
-		return errF(errors.New(str))
+		return usageErr(fmt.Sprintf("did not find subcommand %s", c.CommandStr))
+	}
+
+	if _, ok := c.Command.(StandaloneCommand); ok && c.MeasureDowntime {
+		return usageErr(fmt.Sprintf("subcommand %s does not run against a cluster, so it cannot -measure-downtime", c.CommandStr))
+	}
+	if _, ok := c.Command.(LongRunningCommand); ok && c.MeasureDowntime {
+		return usageErr(fmt.Sprintf("subcommand %s runs until it is interrupted, so it cannot -measure-downtime", c.CommandStr))
+	}
+
+	if c.Selector != "" {
+		if _, err := labels.Parse(c.Selector); err != nil {
+			return usageErr(fmt.Sprintf("could not parse -selector %s: %v", c.Selector, err))
+		}
+	}
+
+	if c.ConnectVia != ConnectViaDirect && c.ConnectVia != ConnectViaPortForward {
+		return usageErr(fmt.Sprintf("-connect-via must be %s or %s, not %s", ConnectViaDirect, ConnectViaPortForward, c.ConnectVia))
+	}
+	if c.ConnectVia == ConnectViaPortForward && c.MeasureDowntime {
+		return usageErr("-measure-downtime writes through a service, so it cannot be used with -connect-via " + ConnectViaPortForward)
+	}
+
+	if len(c.Members) > 0 && (c.Selector != "" || c.OrdinalAnnotation != "") {
+		return usageErr("-selector and -ordinal-annotation describe the pods of a single StatefulSet, so they cannot be used with -member")
+	}
+
+	if c.Remote {
+		if _, ok := c.Command.(StandaloneCommand); ok {
+			return usageErr(fmt.Sprintf("subcommand %s does not run against a cluster, so it cannot be run with -remote", c.CommandStr))
+		}
+		if c.RemoteImage == "" {
+			return usageErr("-remote requires -remote-image")
+		}
+		if _, ok := c.Command.(ConfirmingCommand); ok && !c.Yes {
+			return usageErr(fmt.Sprintf("subcommand %s asks for confirmation on stdin, which a -remote pod does not have, so it requires -yes with -remote", c.CommandStr))
+		}
+		for _, m := range c.Members {
+			if m.Context != "" {
+				return usageErr(fmt.Sprintf("-remote runs in the Kubernetes cluster of the StatefulSet, so it cannot reach -member %s", m))
+			}
+		}
+		if c.TLSConfig != nil && c.TLSConfig.RootCAs != nil && c.RemoteTLSSecret == "" {
+			return usageErr("-tls-ca names a local file, so -remote requires -remote-tls-secret to provide the CA in its pod")
+		}
+	} else if c.RemoteImage != "" || c.RemoteTLSSecret != "" || c.RemoteCredentialsSecret != "" {
+		return usageErr("-remote-image, -remote-tls-secret and -remote-credentials-secret require -remote")
+	}
+
+	if c.WaitForConvergence != 0 && c.WaitForConvergence >= c.Timeout {
+		return usageErr(fmt.Sprintf("-wait-for-convergence %v must be less than -timeout %v", c.WaitForConvergence, c.Timeout))
+	}
+
+	if c.MarkUnknownLag != 0 && !c.MarkUnknown {
+		return usageErr("-mark-unknown-lag requires -mark-unknown")
+	}
+
+	if set.NArg() != 2+nargs {
+		return usageErr(fmt.Sprintf("subcommand %s takes %d argument(s) after the name of the StatefulSet", c.CommandStr, nargs))
 	}
 
 	return nil
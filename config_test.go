@@ -47,6 +47,19 @@ func TestConfigParse(t *testing.T) {
 		err := cfg.Parse(&set, []string{"gracefulfailover", "doltdb"})
 		assert.NoError(t, err)
 	})
+	t.Run("Promote", func(t *testing.T) {
+		var cfg Config
+		var set flag.FlagSet
+		err := cfg.Parse(&set, []string{"promote", "doltdb", "1"})
+		assert.NoError(t, err)
+		assert.Equal(t, Promote{Target: "1"}, cfg.Command)
+	})
+	t.Run("PromoteWithoutTarget", func(t *testing.T) {
+		var cfg Config
+		var set flag.FlagSet
+		err := cfg.Parse(&set, []string{"promote", "doltdb"})
+		assert.Error(t, err)
+	})
 	t.Run("PromoteStandby", func(t *testing.T) {
 		var cfg Config
 		var set flag.FlagSet
@@ -59,12 +72,371 @@ func TestConfigParse(t *testing.T) {
 		err := cfg.Parse(&set, []string{"applyprimarylabels", "doltdb"})
 		assert.NoError(t, err)
 	})
+	t.Run("ApplyPrimaryLabelsMarkUnknown", func(t *testing.T) {
+		var cfg Config
+		var set flag.FlagSet
+		err := cfg.Parse(&set, []string{"-mark-unknown", "-mark-unknown-lag", "30s", "applyprimarylabels", "doltdb"})
+		assert.NoError(t, err)
+		assert.True(t, cfg.MarkUnknown)
+		assert.Equal(t, 30*time.Second, cfg.MarkUnknownLag)
+	})
+	t.Run("MarkUnknownLagWithoutMarkUnknown", func(t *testing.T) {
+		var cfg Config
+		var set flag.FlagSet
+		err := cfg.Parse(&set, []string{"-mark-unknown-lag", "30s", "reconcile", "doltdb"})
+		assert.Error(t, err)
+	})
+	t.Run("AutoFailover", func(t *testing.T) {
+		var cfg Config
+		var set flag.FlagSet
+		err := cfg.Parse(&set, []string{"-failure-threshold", "5", "-failure-window", "1m", "-min-reachable-standbys", "2", "-failover-cooldown", "10m", "autofailover", "doltdb"})
+		assert.NoError(t, err)
+		assert.Equal(t, AutoFailover{}, cfg.Command)
+		assert.Equal(t, 5, cfg.FailureThreshold)
+		assert.Equal(t, time.Minute, cfg.FailureWindow)
+		assert.Equal(t, 2, cfg.MinReachableStandbys)
+		assert.Equal(t, 10*time.Minute, cfg.FailoverCooldown)
+	})
+	t.Run("Upgrade", func(t *testing.T) {
+		var cfg Config
+		var set flag.FlagSet
+		err := cfg.Parse(&set, []string{"-image", "dolthub/dolt-sql-server:1.30.0", "-expect-version", "1.30.0", "upgrade", "doltdb"})
+		assert.NoError(t, err)
+		assert.Equal(t, Upgrade{}, cfg.Command)
+		assert.Equal(t, "dolthub/dolt-sql-server:1.30.0", cfg.Image)
+		assert.Equal(t, "1.30.0", cfg.ExpectVersion)
+	})
+	t.Run("UpgradeWithoutImage", func(t *testing.T) {
+		var cfg Config
+		var set flag.FlagSet
+		err := cfg.Parse(&set, []string{"upgrade", "doltdb"})
+		assert.Error(t, err)
+	})
+	t.Run("Verify", func(t *testing.T) {
+		var cfg Config
+		var set flag.FlagSet
+		err := cfg.Parse(&set, []string{"-wait-for-convergence", "20s", "verify", "doltdb"})
+		assert.NoError(t, err)
+		assert.Equal(t, Verify{}, cfg.Command)
+		assert.Equal(t, 20*time.Second, cfg.WaitForConvergence)
+	})
+	t.Run("VerifyWaitForConvergencePastTimeout", func(t *testing.T) {
+		var cfg Config
+		var set flag.FlagSet
+		err := cfg.Parse(&set, []string{"-wait-for-convergence", "30s", "verify", "doltdb"})
+		assert.Error(t, err)
+	})
+	t.Run("WaitForCaughtUp", func(t *testing.T) {
+		var cfg Config
+		var set flag.FlagSet
+		err := cfg.Parse(&set, []string{"-max-lag", "500ms", "waitforcaughtup", "doltdb"})
+		assert.NoError(t, err)
+		assert.Equal(t, WaitForCaughtUp{}, cfg.Command)
+		assert.Equal(t, 500*time.Millisecond, cfg.MaxLag)
+	})
+	t.Run("Restart", func(t *testing.T) {
+		var cfg Config
+		var set flag.FlagSet
+		err := cfg.Parse(&set, []string{"restart", "doltdb", "2"})
+		assert.NoError(t, err)
+		assert.Equal(t, Restart{Target: "2"}, cfg.Command)
+	})
+	t.Run("RestartWithoutTarget", func(t *testing.T) {
+		var cfg Config
+		var set flag.FlagSet
+		err := cfg.Parse(&set, []string{"restart", "doltdb"})
+		assert.Error(t, err)
+	})
+	t.Run("Drain", func(t *testing.T) {
+		var cfg Config
+		var set flag.FlagSet
+		err := cfg.Parse(&set, []string{"-node", "node-a", "drain", "doltdb"})
+		assert.NoError(t, err)
+		assert.Equal(t, Drain{}, cfg.Command)
+		assert.Equal(t, "node-a", cfg.Node)
+	})
+	t.Run("DrainWithoutNode", func(t *testing.T) {
+		var cfg Config
+		var set flag.FlagSet
+		err := cfg.Parse(&set, []string{"drain", "doltdb"})
+		assert.Error(t, err)
+	})
+	t.Run("GC", func(t *testing.T) {
+		var cfg Config
+		var set flag.FlagSet
+		err := cfg.Parse(&set, []string{"-timeout", "10m", "gc", "doltdb"})
+		assert.NoError(t, err)
+		assert.Equal(t, GC{}, cfg.Command)
+	})
+	t.Run("Backup", func(t *testing.T) {
+		var cfg Config
+		var set flag.FlagSet
+		err := cfg.Parse(&set, []string{"-backup-url", "file:///backups", "-backup-max-lag", "5s", "backup", "doltdb"})
+		assert.NoError(t, err)
+		assert.Equal(t, Backup{}, cfg.Command)
+		assert.Equal(t, "file:///backups", cfg.BackupURL)
+		assert.Equal(t, 5*time.Second, cfg.BackupMaxLag)
+	})
+	t.Run("BackupWithoutURL", func(t *testing.T) {
+		var cfg Config
+		var set flag.FlagSet
+		err := cfg.Parse(&set, []string{"backup", "doltdb"})
+		assert.Error(t, err)
+	})
+	t.Run("Bootstrap", func(t *testing.T) {
+		var cfg Config
+		var set flag.FlagSet
+		err := cfg.Parse(&set, []string{"bootstrap", "doltdb"})
+		assert.NoError(t, err)
+		assert.Equal(t, Bootstrap{Target: "0"}, cfg.Command)
+		assert.Equal(t, 1, cfg.BootstrapEpoch)
+	})
+	t.Run("BootstrapWithTarget", func(t *testing.T) {
+		var cfg Config
+		var set flag.FlagSet
+		err := cfg.Parse(&set, []string{"-bootstrap-epoch", "3", "bootstrap", "doltdb", "2"})
+		assert.NoError(t, err)
+		assert.Equal(t, Bootstrap{Target: "2"}, cfg.Command)
+		assert.Equal(t, 3, cfg.BootstrapEpoch)
+	})
+	t.Run("BootstrapTooManyArgs", func(t *testing.T) {
+		var cfg Config
+		var set flag.FlagSet
+		err := cfg.Parse(&set, []string{"bootstrap", "doltdb", "1", "2"})
+		assert.Error(t, err)
+	})
+	t.Run("Manifests", func(t *testing.T) {
+		var cfg Config
+		var set flag.FlagSet
+		err := cfg.Parse(&set, []string{"-n", "dolt", "-replicas", "3", "-server-tls-secret", "dolt-tls", "-server-require-tls", "manifests", "doltdb"})
+		assert.NoError(t, err)
+		assert.Equal(t, Manifests{}, cfg.Command)
+		assert.Equal(t, 3, cfg.Replicas)
+		assert.Equal(t, "10Gi", cfg.StorageSize)
+		assert.Equal(t, "dolt-tls", cfg.ServerTLSSecret)
+	})
+	t.Run("ManifestsRequireTLSWithoutSecret", func(t *testing.T) {
+		var cfg Config
+		var set flag.FlagSet
+		err := cfg.Parse(&set, []string{"-server-require-tls", "manifests", "doltdb"})
+		assert.Error(t, err)
+	})
+	t.Run("Scale", func(t *testing.T) {
+		var cfg Config
+		var set flag.FlagSet
+		err := cfg.Parse(&set, []string{"scale", "doltdb", "3"})
+		assert.NoError(t, err)
+		assert.Equal(t, Scale{Replicas: 3}, cfg.Command)
+	})
+	t.Run("ScaleWithoutReplicas", func(t *testing.T) {
+		var cfg Config
+		var set flag.FlagSet
+		err := cfg.Parse(&set, []string{"scale", "doltdb"})
+		assert.Error(t, err)
+	})
+	t.Run("ScaleInvalidReplicas", func(t *testing.T) {
+		var cfg Config
+		var set flag.FlagSet
+		err := cfg.Parse(&set, []string{"scale", "doltdb", "many"})
+		assert.Error(t, err)
+	})
+	t.Run("ScaleToOne", func(t *testing.T) {
+		var cfg Config
+		var set flag.FlagSet
+		err := cfg.Parse(&set, []string{"scale", "doltdb", "1"})
+		assert.Error(t, err)
+	})
+	t.Run("MeasureDowntime", func(t *testing.T) {
+		var cfg Config
+		var set flag.FlagSet
+		err := cfg.Parse(&set, []string{"-measure-downtime", "-primary-service", "dolt-primary.dolt", "gracefulfailover", "doltdb"})
+		assert.NoError(t, err)
+		assert.True(t, cfg.MeasureDowntime)
+		assert.Equal(t, "dolt-primary.dolt", cfg.PrimaryService)
+	})
+	t.Run("MeasureDowntimeStandalone", func(t *testing.T) {
+		var cfg Config
+		var set flag.FlagSet
+		err := cfg.Parse(&set, []string{"-measure-downtime", "manifests", "doltdb"})
+		assert.Error(t, err)
+	})
+	t.Run("MeasureDowntimeLongRunning", func(t *testing.T) {
+		var cfg Config
+		var set flag.FlagSet
+		err := cfg.Parse(&set, []string{"-measure-downtime", "reconcile", "doltdb"})
+		assert.Error(t, err)
+	})
+	t.Run("Check", func(t *testing.T) {
+		var cfg Config
+		var set flag.FlagSet
+		err := cfg.Parse(&set, []string{"-max-lag", "5s", "-require-standbys", "2", "-fail-on-current-error", "check", "doltdb"})
+		assert.NoError(t, err)
+		assert.Equal(t, Check{}, cfg.Command)
+		assert.Equal(t, 5*time.Second, cfg.MaxLag)
+		assert.Equal(t, 2, cfg.RequireStandbys)
+		assert.True(t, cfg.FailOnCurrentError)
+	})
+	t.Run("CheckUsageError", func(t *testing.T) {
+		var cfg Config
+		var set flag.FlagSet
+		err := cfg.Parse(&set, []string{"check", "doltdb", "extra"})
+		var exitErr *ExitError
+		if assert.ErrorAs(t, err, &exitErr) {
+			assert.Equal(t, CheckUnknown, exitErr.Code)
+		}
+	})
+	t.Run("Reseed", func(t *testing.T) {
+		var cfg Config
+		var set flag.FlagSet
+		err := cfg.Parse(&set, []string{"-yes", "reseed", "doltdb", "dolt-1"})
+		assert.NoError(t, err)
+		assert.Equal(t, Reseed{Target: "dolt-1"}, cfg.Command)
+		assert.True(t, cfg.Yes)
+	})
+	t.Run("Reconcile", func(t *testing.T) {
+		var cfg Config
+		var set flag.FlagSet
+		err := cfg.Parse(&set, []string{"-interval", "5s", "reconcile", "doltdb"})
+		assert.NoError(t, err)
+		assert.Equal(t, 5*time.Second, cfg.Interval)
+	})
+	t.Run("ResolveBrokenConfig", func(t *testing.T) {
+		var cfg Config
+		var set flag.FlagSet
+		err := cfg.Parse(&set, []string{"-yes", "resolvebrokenconfig", "doltdb"})
+		assert.NoError(t, err)
+		assert.True(t, cfg.Yes)
+	})
 	t.Run("RollingRestart", func(t *testing.T) {
 		var cfg Config
 		var set flag.FlagSet
 		err := cfg.Parse(&set, []string{"rollingrestart", "doltdb"})
 		assert.NoError(t, err)
 	})
+	t.Run("Status", func(t *testing.T) {
+		var cfg Config
+		var set flag.FlagSet
+		err := cfg.Parse(&set, []string{"status", "doltdb"})
+		assert.NoError(t, err)
+	})
+	t.Run("Kubeconfig", func(t *testing.T) {
+		var cfg Config
+		var set flag.FlagSet
+		err := cfg.Parse(&set, []string{"-kubeconfig", "/home/dolt/.kube/config", "-context", "kind-kind", "-n", "dolt", "status", "doltdb"})
+		assert.NoError(t, err)
+		assert.Equal(t, "/home/dolt/.kube/config", cfg.Kubeconfig)
+		assert.Equal(t, "kind-kind", cfg.Context)
+	})
+	t.Run("ConnectViaPortForward", func(t *testing.T) {
+		var cfg Config
+		var set flag.FlagSet
+		err := cfg.Parse(&set, []string{"-connect-via", "port-forward", "status", "doltdb"})
+		assert.NoError(t, err)
+		assert.Equal(t, ConnectViaPortForward, cfg.ConnectVia)
+	})
+	t.Run("ConnectViaUnknown", func(t *testing.T) {
+		var cfg Config
+		var set flag.FlagSet
+		err := cfg.Parse(&set, []string{"-connect-via", "vpn", "status", "doltdb"})
+		assert.Error(t, err)
+	})
+	t.Run("ConnectViaPortForwardMeasureDowntime", func(t *testing.T) {
+		var cfg Config
+		var set flag.FlagSet
+		err := cfg.Parse(&set, []string{"-connect-via", "port-forward", "-measure-downtime", "gracefulfailover", "doltdb"})
+		assert.Error(t, err)
+	})
+	t.Run("Selector", func(t *testing.T) {
+		var cfg Config
+		var set flag.FlagSet
+		err := cfg.Parse(&set, []string{"-selector", "app=dolt,site in (east, west)", "-ordinal-annotation", "dolthub.com/ordinal", "status", "dolt"})
+		assert.NoError(t, err)
+		assert.Equal(t, "app=dolt,site in (east, west)", cfg.Selector)
+		assert.Equal(t, "dolthub.com/ordinal", cfg.OrdinalAnnotation)
+	})
+	t.Run("InvalidSelector", func(t *testing.T) {
+		var cfg Config
+		var set flag.FlagSet
+		err := cfg.Parse(&set, []string{"-selector", "app in dolt", "status", "dolt"})
+		assert.Error(t, err)
+	})
+	t.Run("Members", func(t *testing.T) {
+		var cfg Config
+		var set flag.FlagSet
+		err := cfg.Parse(&set, []string{"-n", "dolt", "-member", "dolt/dolt-dr", "-member", "arn:aws:eks:us-west-2:012345678901:cluster/dr:dolt/dolt", "gracefulfailover", "dolt"})
+		assert.NoError(t, err)
+		assert.Equal(t, []ClusterMember{
+			{Namespace: "dolt", StatefulSet: "dolt-dr"},
+			{Context: "arn:aws:eks:us-west-2:012345678901:cluster/dr", Namespace: "dolt", StatefulSet: "dolt"},
+		}, cfg.Members)
+	})
+	t.Run("InvalidMember", func(t *testing.T) {
+		var cfg Config
+		var set flag.FlagSet
+		err := cfg.Parse(&set, []string{"-member", "dolt-dr", "gracefulfailover", "dolt"})
+		assert.Error(t, err)
+	})
+	t.Run("MemberWithSelector", func(t *testing.T) {
+		var cfg Config
+		var set flag.FlagSet
+		err := cfg.Parse(&set, []string{"-member", "dolt/dolt-dr", "-selector", "app=dolt", "gracefulfailover", "dolt"})
+		assert.Error(t, err)
+	})
+	t.Run("MemberWithOrdinalAnnotation", func(t *testing.T) {
+		var cfg Config
+		var set flag.FlagSet
+		err := cfg.Parse(&set, []string{"-member", "dolt/dolt-dr", "-ordinal-annotation", "dolthub.com/ordinal", "gracefulfailover", "dolt"})
+		assert.Error(t, err)
+	})
+	t.Run("RemoteMemberInOtherContext", func(t *testing.T) {
+		var cfg Config
+		var set flag.FlagSet
+		err := cfg.Parse(&set, []string{"-remote", "-remote-image", "doltclusterctl:latest", "-member", "dr:dolt/dolt", "gracefulfailover", "dolt"})
+		assert.Error(t, err)
+	})
+	t.Run("Remote", func(t *testing.T) {
+		var cfg Config
+		var set flag.FlagSet
+		err := cfg.Parse(&set, []string{"-remote", "-remote-image", "doltclusterctl:latest", "-remote-credentials-secret", "dolt-credentials", "-n", "dolt", "gracefulfailover", "dolt"})
+		assert.NoError(t, err)
+		assert.True(t, cfg.Remote)
+		assert.Equal(t, "doltclusterctl:latest", cfg.RemoteImage)
+		assert.Equal(t, "doltclusterctl", cfg.RemoteServiceAccount)
+		assert.Equal(t, "dolt-credentials", cfg.RemoteCredentialsSecret)
+	})
+	t.Run("RemoteWithoutImage", func(t *testing.T) {
+		var cfg Config
+		var set flag.FlagSet
+		err := cfg.Parse(&set, []string{"-remote", "status", "dolt"})
+		assert.Error(t, err)
+	})
+	t.Run("RemoteImageWithoutRemote", func(t *testing.T) {
+		var cfg Config
+		var set flag.FlagSet
+		err := cfg.Parse(&set, []string{"-remote-image", "doltclusterctl:latest", "status", "dolt"})
+		assert.Error(t, err)
+	})
+	t.Run("RemoteWithoutYes", func(t *testing.T) {
+		for _, args := range [][]string{{"resolvebrokenconfig", "dolt"}, {"reseed", "dolt", "1"}} {
+			var cfg Config
+			var set flag.FlagSet
+			err := cfg.Parse(&set, append([]string{"-remote", "-remote-image", "doltclusterctl:latest"}, args...))
+			assert.Error(t, err)
+		}
+	})
+	t.Run("RemoteWithYes", func(t *testing.T) {
+		var cfg Config
+		var set flag.FlagSet
+		err := cfg.Parse(&set, []string{"-remote", "-remote-image", "doltclusterctl:latest", "-yes", "reseed", "dolt", "1"})
+		assert.NoError(t, err)
+		assert.True(t, cfg.Yes)
+	})
+	t.Run("RemoteManifests", func(t *testing.T) {
+		var cfg Config
+		var set flag.FlagSet
+		err := cfg.Parse(&set, []string{"-remote", "-remote-image", "doltclusterctl:latest", "manifests", "dolt"})
+		assert.Error(t, err)
+	})
 	t.Run("UnrecognizedCommand", func(t *testing.T) {
 		var cfg Config
 		var set flag.FlagSet
@@ -241,6 +613,13 @@ func TestConfigFlagSet(t *testing.T) {
 			assert.Error(t, err)
 		})
 	})
+	t.Run("FailoverTo", func(t *testing.T) {
+		var cfg Config
+		var set flag.FlagSet
+		err := cfg.Parse(&set, []string{"-to", "2", "gracefulfailover", "doltdb"})
+		assert.NoError(t, err)
+		assert.Equal(t, "2", cfg.FailoverTo)
+	})
 	t.Run("Timeout", func(t *testing.T) {
 		var cfg Config
 		var set flag.FlagSet
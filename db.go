@@ -16,25 +16,55 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
 	"database/sql"
 	"errors"
 	"fmt"
+	"maps"
 	"net/url"
 	"os"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/cenkalti/backoff/v4"
+	"github.com/go-sql-driver/mysql"
 )
 
 func OpenDB(ctx context.Context, cfg *Config, instance Instance) (*sql.DB, error) {
-	hostname := instance.Hostname()
-	port := instance.Port()
+	hostname, port, err := ConnectAddress(ctx, instance)
+	if err != nil {
+		return nil, err
+	}
 	dsn := RenderDSN(cfg, hostname, port)
+	if hostname != instance.Hostname() {
+		dsn = RenderTunneledDSN(cfg, hostname, port, instance.Hostname())
+	}
 	return sql.Open("mysql", dsn)
 }
 
+// The host and port at which to connect to |instance|'s sql-server; its
+// Hostname() and Port() unless it is a ConnectableInstance.
+func ConnectAddress(ctx context.Context, instance Instance) (string, int, error) {
+	if c, ok := instance.(ConnectableInstance); ok {
+		return c.ConnectAddress(ctx)
+	}
+	return instance.Hostname(), instance.Port(), nil
+}
+
 func RenderDSN(cfg *Config, hostname string, port int) string {
+	return renderDSN(cfg, hostname, port, "")
+}
+
+// Like RenderDSN, for a connection to |hostname| and |port| which is
+// tunneled to the server at |serverName|, for example a port-forward on
+// localhost. Unless -tls-server-name is given, TLS verifies the server's
+// certificate against |serverName| instead of |hostname|.
+func RenderTunneledDSN(cfg *Config, hostname string, port int, serverName string) string {
+	return renderDSN(cfg, hostname, port, serverName)
+}
+
+func renderDSN(cfg *Config, hostname string, port int, serverName string) string {
 	user := os.Getenv("DOLT_USERNAME")
 	if user == "" {
 		user = "root"
@@ -49,6 +79,8 @@ func RenderDSN(cfg *Config, hostname string, port int) string {
 	params["parseTime"] = []string{"true"}
 	if cfg.TLSInsecure {
 		params["tls"] = []string{"skip-verify"}
+	} else if serverName != "" && (cfg.TLSConfig != nil || cfg.TLSVerified) && (cfg.TLSConfig == nil || cfg.TLSConfig.ServerName == "") {
+		params["tls"] = []string{registerTunnelTLSConfig(cfg, serverName)}
 	} else if cfg.TLSConfig != nil {
 		// TODO: This is spookily coupled to the config name in main
 		params["tls"] = []string{"custom"}
@@ -58,6 +90,19 @@ func RenderDSN(cfg *Config, hostname string, port int) string {
 	return fmt.Sprintf("%s@tcp(%s:%d)/dolt_cluster?%s", authority, hostname, port, params.Encode())
 }
 
+// Registers a TLS config with the mysql driver which verifies the server
+// as |serverName|, and returns its name.
+func registerTunnelTLSConfig(cfg *Config, serverName string) string {
+	config := &tls.Config{}
+	if cfg.TLSConfig != nil {
+		config = cfg.TLSConfig.Clone()
+	}
+	config.ServerName = serverName
+	name := "tunnel-" + serverName
+	mysql.RegisterTLSConfig(name, config)
+	return name
+}
+
 func CallAssumeRole(ctx context.Context, cfg *Config, instance Instance, role string, epoch int) error {
 	db, err := OpenDB(ctx, cfg, instance)
 	if err != nil {
@@ -95,44 +140,49 @@ func CallAssumeRole(ctx context.Context, cfg *Config, instance Instance, role st
 	return nil
 }
 
-func CallTransitionToStandby(ctx context.Context, cfg *Config, instance Instance, epoch int, dbstates []DBState) (int, error) {
+type TransitionResult struct {
+	CaughtUp  int
+	Database  string
+	Remote    string
+	RemoteURL string
+	Parsed    *url.URL
+}
+
+// Calls dolt_cluster_transition_to_standby on |instance|. Returns the index
+// in |dbstates| of the standby which was caught up on the most databases,
+// and which should become the next primary, along with the indexes of every
+// standby which was caught up on all of its databases.
+func CallTransitionToStandby(ctx context.Context, cfg *Config, instance Instance, epoch int, dbstates []DBState) (int, []int, error) {
 	db, err := OpenDB(ctx, cfg, instance)
 	if err != nil {
-		return -1, err
+		return -1, nil, err
 	}
 	defer db.Close()
 
 	conn, err := db.Conn(ctx)
 	if err != nil {
-		return -1, err
+		return -1, nil, err
 	}
 	defer conn.Close()
 
-	type TransitionResult struct {
-		CaughtUp  int
-		Database  string
-		Remote    string
-		RemoteURL string
-		Parsed    *url.URL
-	}
 	var results []TransitionResult
 
 	q := fmt.Sprintf("CALL DOLT_CLUSTER_TRANSITION_TO_STANDBY('%d', '%d')", epoch, cfg.MinCaughtUpStandbys)
 	rows, err := conn.QueryContext(ctx, q)
 	if err != nil {
-		return -1, err
+		return -1, nil, err
 	}
 	defer rows.Close()
 	for rows.Next() {
 		var res TransitionResult
 		err = rows.Scan(&res.CaughtUp, &res.Database, &res.Remote, &res.RemoteURL)
 		if err != nil {
-			return -1, err
+			return -1, nil, err
 		}
 		results = append(results, res)
 	}
 	if rows.Err() != nil {
-		return -1, rows.Err()
+		return -1, nil, rows.Err()
 	}
 
 	numCaughtUp := make(map[string]int)
@@ -140,13 +190,15 @@ func CallTransitionToStandby(ctx context.Context, cfg *Config, instance Instance
 		var err error
 		results[i].Parsed, err = url.Parse(results[i].RemoteURL)
 		if err != nil {
-			return -1, err
+			return -1, nil, err
 		}
 		if results[i].CaughtUp == 1 {
 			numCaughtUp[results[i].Parsed.Host] = numCaughtUp[results[i].Parsed.Host] + 1
 		}
 	}
 
+	caughtUp := CaughtUpStandbys(results, dbstates)
+
 	var maxCaughtUpHost string
 	var maxCaughtUp int
 	for k, v := range numCaughtUp {
@@ -165,18 +217,78 @@ func CallTransitionToStandby(ctx context.Context, cfg *Config, instance Instance
 	}
 
 	if maxCaughtUpParsedURL == nil {
-		return -1, fmt.Errorf("internal error: did not find caught up URL of the caught up host: %s", maxCaughtUpHost)
+		return -1, caughtUp, fmt.Errorf("internal error: did not find caught up URL of the caught up host: %s", maxCaughtUpHost)
 	}
 	caughtUpHostname := maxCaughtUpParsedURL.Hostname()
 
+	if i := InstanceForRemoteHost(dbstates, caughtUpHostname); i != -1 {
+		return i, caughtUp, nil
+	}
+
+	return -1, caughtUp, fmt.Errorf("internal error: did not find caught up URL of the caught up host: %s", maxCaughtUpHost)
+}
+
+// Returns the indexes in |dbstates| of the standbys which |results| reports
+// as caught up on every database. |results| must already be Parsed.
+func CaughtUpStandbys(results []TransitionResult, dbstates []DBState) []int {
+	caughtUp := make(map[string]bool)
+	for _, res := range results {
+		host := res.Parsed.Hostname()
+		if v, ok := caughtUp[host]; !ok || v {
+			caughtUp[host] = res.CaughtUp == 1
+		}
+	}
+
+	var ret []int
+	for i := range dbstates {
+		for host, v := range caughtUp {
+			if v && InstanceForRemoteHost(dbstates, host) == i {
+				ret = append(ret, i)
+				break
+			}
+		}
+	}
+	return ret
+}
+
+// Returns the index of the instance in |dbstates| which is reached at
+// |hostname|, the host of a standby remote URL, or -1 if there is no such
+// instance. The remote URL may use a shorter, search-domain relative form of
+// the hostname of the instance.
+func InstanceForRemoteHost(dbstates []DBState, hostname string) int {
 	for i, dbs := range dbstates {
-		instanceHostname := dbs.Instance.Hostname()
-		if instanceHostname == caughtUpHostname || strings.HasPrefix(instanceHostname, caughtUpHostname) {
-			return i, nil
+		if RemoteHostMatches(dbs.Instance.Hostname(), hostname) {
+			return i
 		}
 	}
+	return -1
+}
+
+// Returns true if |remoteHost|, the host of a standby remote URL, reaches
+// the instance at |hostname|.
+func RemoteHostMatches(hostname, remoteHost string) bool {
+	return hostname == remoteHost || strings.HasPrefix(hostname, remoteHost+".")
+}
 
-	return -1, fmt.Errorf("internal error: did not find caught up URL of the caught up host: %s", maxCaughtUpHost)
+// Returns the dolt_cluster_status rows of the server at |primary| which
+// describe its replication to the server at |standby|.
+func StandbyStatusRows(dbstates []DBState, primary, standby int) []StatusRow {
+	var ret []StatusRow
+	for _, status := range dbstates[primary].Status {
+		for _, remote := range dbstates[primary].Remotes {
+			if remote.Database != status.Database || remote.Name != status.Remote {
+				continue
+			}
+			parsed, err := url.Parse(remote.URL)
+			if err != nil {
+				continue
+			}
+			if InstanceForRemoteHost(dbstates, parsed.Hostname()) == standby {
+				ret = append(ret, status)
+			}
+		}
+	}
+	return ret
 }
 
 func LoadDBState(ctx context.Context, cfg *Config, instance Instance) DBState {
@@ -343,6 +455,277 @@ func loadRoleAndEpoch(ctx context.Context, conn *sql.Conn) (string, int, error)
 	return role, epoch, nil
 }
 
+// The branch heads of a single database on a server.
+type DatabaseHeads struct {
+	Database string
+	// The commit hash at the head of each branch, keyed by branch name.
+	Branches map[string]string
+	// The hash of the working set of each branch, keyed by branch name.
+	// Only loaded by LoadWorkingSets.
+	WorkingSets map[string]string
+}
+
+// Loads the head of every branch of every user database on |instance|.
+// The returned databases are sorted by name.
+func LoadHeads(ctx context.Context, cfg *Config, instance Instance) ([]DatabaseHeads, error) {
+	db, err := OpenDB(ctx, cfg, instance)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	names, err := loadDatabaseNames(ctx, conn)
+	if err != nil {
+		return nil, fmt.Errorf("error listing databases on %s: %w", instance.Name(), err)
+	}
+
+	var ret []DatabaseHeads
+	for _, name := range names {
+		branches, err := loadBranchHeads(ctx, conn, name)
+		if err != nil {
+			return nil, fmt.Errorf("error loading branches of database %s on %s: %w", name, instance.Name(), err)
+		}
+		ret = append(ret, DatabaseHeads{Database: name, Branches: branches})
+	}
+	return ret, nil
+}
+
+// Loads the hash of the working set of every branch in |heads| on
+// |instance|, filling in WorkingSets.
+func LoadWorkingSets(ctx context.Context, cfg *Config, instance Instance, heads []DatabaseHeads) error {
+	db, err := OpenDB(ctx, cfg, instance)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	for i := range heads {
+		heads[i].WorkingSets = make(map[string]string)
+		for branch := range heads[i].Branches {
+			_, err := conn.ExecContext(ctx, fmt.Sprintf("USE `%s/%s`", heads[i].Database, branch))
+			if err != nil {
+				return fmt.Errorf("error loading working set of branch %s of database %s on %s: %w", branch, heads[i].Database, instance.Name(), err)
+			}
+			var hash string
+			err = conn.QueryRowContext(ctx, "SELECT dolt_hashof_db()").Scan(&hash)
+			if err != nil {
+				return fmt.Errorf("error loading working set of branch %s of database %s on %s: %w", branch, heads[i].Database, instance.Name(), err)
+			}
+			heads[i].WorkingSets[branch] = hash
+		}
+	}
+	return nil
+}
+
+// Calls dolt_gc() in database |dbname| on |instance|. It uses a new
+// connection, since dolt_gc() can invalidate the connections which were
+// open while it ran.
+func CallGC(ctx context.Context, cfg *Config, instance Instance, dbname string) error {
+	db, err := OpenDB(ctx, cfg, instance)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = conn.ExecContext(ctx, fmt.Sprintf("USE `%s`", dbname))
+	if err != nil {
+		return err
+	}
+	_, err = conn.ExecContext(ctx, "CALL dolt_gc()")
+	return err
+}
+
+// Syncs database |dbname| on |instance| to the backup at |url| with
+// dolt_backup('sync-url', ...), and returns the head of every branch it
+// backed up. The heads are loaded on the same connection just before and
+// after the sync. If they moved while it ran, for example because the
+// instance is a standby receiving writes, it syncs again, up to
+// |attempts| times, and otherwise returns the heads from before the last
+// sync, which the backup includes.
+func CallBackupSyncURL(ctx context.Context, cfg *Config, instance Instance, dbname, url string, attempts int) (map[string]string, error) {
+	db, err := OpenDB(ctx, cfg, instance)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	_, err = conn.ExecContext(ctx, fmt.Sprintf("USE `%s`", dbname))
+	if err != nil {
+		return nil, err
+	}
+	before, err := loadBranchHeads(ctx, conn, dbname)
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; ; i++ {
+		_, err = conn.ExecContext(ctx, "CALL dolt_backup('sync-url', ?)", url)
+		if err != nil {
+			return nil, err
+		}
+		after, err := loadBranchHeads(ctx, conn, dbname)
+		if err != nil {
+			return nil, err
+		}
+		if maps.Equal(before, after) || i+1 >= attempts {
+			return before, nil
+		}
+		before = after
+	}
+}
+
+// Runs each of |stmts| in order on a single connection to |instance|.
+func ExecStatements(ctx context.Context, cfg *Config, instance Instance, stmts ...string) error {
+	db, err := OpenDB(ctx, cfg, instance)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	for _, stmt := range stmts {
+		_, err := conn.ExecContext(ctx, stmt)
+		if err != nil {
+			return fmt.Errorf("error running %q on %s: %w", stmt, instance.Name(), err)
+		}
+	}
+	return nil
+}
+
+// Returns the names of the user databases on |instance|, sorted by name.
+func LoadDatabaseNames(ctx context.Context, cfg *Config, instance Instance) ([]string, error) {
+	db, err := OpenDB(ctx, cfg, instance)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	names, err := loadDatabaseNames(ctx, conn)
+	if err != nil {
+		return nil, fmt.Errorf("error listing databases on %s: %w", instance.Name(), err)
+	}
+	return names, nil
+}
+
+// Returns true if the commit |ancestor| is an ancestor of, or the same
+// commit as, |descendant| in database |dbname| on |instance|. Returns an
+// error if either commit is not in the database.
+func IsAncestor(ctx context.Context, cfg *Config, instance Instance, dbname, ancestor, descendant string) (bool, error) {
+	db, err := OpenDB(ctx, cfg, instance)
+	if err != nil {
+		return false, err
+	}
+	defer db.Close()
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	_, err = conn.ExecContext(ctx, fmt.Sprintf("USE `%s`", dbname))
+	if err != nil {
+		return false, err
+	}
+	var base string
+	err = conn.QueryRowContext(ctx, "SELECT dolt_merge_base(?, ?)", ancestor, descendant).Scan(&base)
+	if err != nil {
+		return false, err
+	}
+	return base == ancestor, nil
+}
+
+// Returns the names of the user databases on the server, sorted by name.
+func loadDatabaseNames(ctx context.Context, conn *sql.Conn) ([]string, error) {
+	rows, err := conn.QueryContext(ctx, "SHOW DATABASES")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var ret []string
+	for rows.Next() {
+		var name string
+		err = rows.Scan(&name)
+		if err != nil {
+			return nil, err
+		}
+		if !IsSystemDatabase(name) {
+			ret = append(ret, name)
+		}
+	}
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+	sort.Strings(ret)
+	return ret, nil
+}
+
+// Returns true for the databases which every sql-server has and which are
+// not replicated, and for the heartbeat database of -measure-downtime, which
+// is written to while the operation runs and is dropped afterwards.
+func IsSystemDatabase(name string) bool {
+	switch strings.ToLower(name) {
+	case "information_schema", "mysql", "performance_schema", "sys", "dolt_cluster", HeartbeatDatabase:
+		return true
+	}
+	return false
+}
+
+func loadBranchHeads(ctx context.Context, conn *sql.Conn, db string) (map[string]string, error) {
+	rows, err := conn.QueryContext(ctx, fmt.Sprintf("SELECT name, hash FROM `%s`.dolt_branches", db))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	ret := make(map[string]string)
+	for rows.Next() {
+		var name, hash string
+		err = rows.Scan(&name, &hash)
+		if err != nil {
+			return nil, err
+		}
+		ret[name] = hash
+	}
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+	return ret, nil
+}
+
 type StatusRow struct {
 	Database       string
 	Role           string
@@ -0,0 +1,389 @@
+// Copyright 2023 DoltHub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"log"
+	"slices"
+	"sync"
+	"time"
+)
+
+// The database the -measure-downtime heartbeats are written to.
+const HeartbeatDatabase = "doltclusterctl_heartbeat"
+
+// How often a heartbeat is written to the primary, and read back from each
+// server.
+const heartbeatInterval = 100 * time.Millisecond
+
+// A span of time during which heartbeat writes to the primary failed. It
+// runs from the first failed write to the next write which succeeded, or
+// to the end of the measurement if none did.
+type DowntimeWindow struct {
+	Start        time.Time
+	End          time.Time
+	FailedWrites int
+	LastError    string
+	// True if writes were still failing when the measurement stopped.
+	Open bool
+}
+
+func (w DowntimeWindow) Duration() time.Duration {
+	return w.End.Sub(w.Start)
+}
+
+// A line the command logged, and when.
+type LoggedStep struct {
+	At   time.Time
+	Line string
+}
+
+type DowntimeReport struct {
+	Written      int
+	Acknowledged int
+	// The acknowledged heartbeats which the primary did not have at the
+	// end of the measurement.
+	Lost []int64
+	// Set if the heartbeats on the primary could not be read at the end
+	// of the measurement, in which case Lost is unknown.
+	LostErr error
+
+	Windows []DowntimeWindow
+
+	// The longest a standby took to have an acknowledged heartbeat.
+	MaxDelay    time.Duration
+	MaxDelayPod string
+
+	Steps []LoggedStep
+}
+
+// Records each line written to the log, so that the report can show what
+// the command was doing while writes were unavailable.
+type stepRecorder struct {
+	mu    sync.Mutex
+	steps []LoggedStep
+}
+
+func (r *stepRecorder) Write(p []byte) (int, error) {
+	line := string(bytes.TrimRight(p, "\n"))
+	// The report shows when each step was logged itself.
+	const prefix = "2006/01/02 15:04:05 "
+	if log.Flags() == log.LstdFlags && len(line) >= len(prefix) {
+		line = line[len(prefix):]
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.steps = append(r.steps, LoggedStep{At: time.Now(), Line: line})
+	return len(p), nil
+}
+
+// A DowntimeMeasurement writes heartbeats through the primary service while
+// a command runs, and reads them back from every server, to measure how
+// long writes are unavailable and how far behind the standbys fall.
+type DowntimeMeasurement struct {
+	cfg      *Config
+	hostname string
+	port     int
+	first    int64
+
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+	logOutput io.Writer
+	steps     *stepRecorder
+
+	mu      sync.Mutex
+	acked   map[int64]time.Time
+	current *DowntimeWindow
+	report  DowntimeReport
+}
+
+// Creates the heartbeat table through the primary service, -primary-service
+// or by default the -rw service of the StatefulSet, and starts writing
+// heartbeats to it and reading them back from every instance of |cluster|.
+// Everything logged until Stop is recorded for the report.
+func StartDowntimeMeasurement(ctx context.Context, cfg *Config, cluster Cluster) (*DowntimeMeasurement, error) {
+	m := &DowntimeMeasurement{
+		cfg:      cfg,
+		hostname: cfg.PrimaryService,
+		port:     cluster.Instance(0).Port(),
+		steps:    &stepRecorder{},
+		acked:    make(map[int64]time.Time),
+	}
+	if m.hostname == "" {
+		m.hostname = cfg.StatefulSetName + "-rw." + cfg.Namespace
+	}
+
+	db, err := sql.Open("mysql", RenderDSN(cfg, m.hostname, m.port))
+	if err != nil {
+		return nil, err
+	}
+	for _, stmt := range []string{
+		fmt.Sprintf("CREATE DATABASE IF NOT EXISTS `%s`", HeartbeatDatabase),
+		fmt.Sprintf("CREATE TABLE IF NOT EXISTS `%s`.`heartbeat` (seq bigint PRIMARY KEY, written_at datetime(6))", HeartbeatDatabase),
+	} {
+		_, err := db.ExecContext(ctx, stmt)
+		if err != nil {
+			db.Close()
+			return nil, fmt.Errorf("error creating heartbeat table through %s: %w", m.hostname, err)
+		}
+	}
+	err = db.QueryRowContext(ctx, fmt.Sprintf("SELECT COALESCE(MAX(seq), 0) + 1 FROM `%s`.`heartbeat`", HeartbeatDatabase)).Scan(&m.first)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("error reading heartbeat table through %s: %w", m.hostname, err)
+	}
+	// Every write makes a new connection, so that it goes through the
+	// service to whichever server is currently the primary.
+	db.SetMaxIdleConns(0)
+
+	ctx, m.cancel = context.WithCancel(ctx)
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		defer db.Close()
+		m.writeHeartbeats(ctx, db)
+	}()
+	for i := 0; i < cluster.NumReplicas(); i++ {
+		instance := cluster.Instance(i)
+		name, dsn := instance.Name(), RenderDSN(cfg, instance.Hostname(), instance.Port())
+		m.wg.Add(1)
+		go func() {
+			defer m.wg.Done()
+			m.readHeartbeats(ctx, name, dsn)
+		}()
+	}
+
+	m.logOutput = log.Writer()
+	log.SetOutput(io.MultiWriter(m.logOutput, m.steps))
+	log.Printf("measuring downtime with heartbeats written through %s", m.hostname)
+	return m, nil
+}
+
+func (m *DowntimeMeasurement) writeHeartbeats(ctx context.Context, db *sql.DB) {
+	ticker := time.NewTicker(heartbeatInterval)
+	defer ticker.Stop()
+	for seq := m.first; ; seq++ {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+		attempt := time.Now()
+		writeCtx, cancel := context.WithTimeout(ctx, time.Second)
+		_, err := db.ExecContext(writeCtx, fmt.Sprintf("INSERT INTO `%s`.`heartbeat` VALUES (?, ?)", HeartbeatDatabase), seq, attempt.UTC())
+		cancel()
+		if ctx.Err() != nil {
+			return
+		}
+		m.recordWrite(seq, attempt, time.Now(), err)
+	}
+}
+
+func (m *DowntimeMeasurement) recordWrite(seq int64, attempt, done time.Time, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.report.Written++
+	if err != nil {
+		if m.current == nil {
+			m.current = &DowntimeWindow{Start: attempt}
+		}
+		m.current.FailedWrites++
+		m.current.LastError = err.Error()
+		return
+	}
+	m.report.Acknowledged++
+	m.acked[seq] = done
+	if m.current != nil {
+		m.current.End = done
+		m.report.Windows = append(m.report.Windows, *m.current)
+		m.current = nil
+	}
+}
+
+func (m *DowntimeMeasurement) readHeartbeats(ctx context.Context, name, dsn string) {
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		return
+	}
+	defer db.Close()
+
+	ticker := time.NewTicker(heartbeatInterval)
+	defer ticker.Stop()
+	seen := m.first - 1
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+		var role string
+		var latest sql.NullInt64
+		readCtx, cancel := context.WithTimeout(ctx, time.Second)
+		err := db.QueryRowContext(readCtx, fmt.Sprintf("SELECT @@global.dolt_cluster_role, (SELECT MAX(seq) FROM `%s`.`heartbeat`)", HeartbeatDatabase)).Scan(&role, &latest)
+		cancel()
+		if err != nil || !latest.Valid || latest.Int64 <= seen {
+			continue
+		}
+		if role == "standby" {
+			m.recordRead(name, seen, latest.Int64, time.Now())
+		}
+		seen = latest.Int64
+	}
+}
+
+// Records that the standby |name| had every heartbeat after |seen| up to
+// |latest| at |at|.
+func (m *DowntimeMeasurement) recordRead(name string, seen, latest int64, at time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for seq := seen + 1; seq <= latest; seq++ {
+		acked, ok := m.acked[seq]
+		if !ok {
+			continue
+		}
+		if delay := at.Sub(acked); delay > m.report.MaxDelay {
+			m.report.MaxDelay = delay
+			m.report.MaxDelayPod = name
+		}
+	}
+}
+
+// Stops writing and reading heartbeats and stops recording the log. Checks
+// that the primary has every acknowledged heartbeat, drops the heartbeat
+// database, and returns the report.
+func (m *DowntimeMeasurement) Stop(ctx context.Context) DowntimeReport {
+	m.cancel()
+	m.wg.Wait()
+	log.SetOutput(m.logOutput)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.current != nil {
+		m.current.End = time.Now()
+		m.current.Open = true
+		m.report.Windows = append(m.report.Windows, *m.current)
+		m.current = nil
+	}
+	m.steps.mu.Lock()
+	m.report.Steps = m.steps.steps
+	m.steps.mu.Unlock()
+
+	present, err := m.loadHeartbeats(ctx)
+	if err != nil {
+		m.report.LostErr = err
+	} else {
+		for seq := range m.acked {
+			if !present[seq] {
+				m.report.Lost = append(m.report.Lost, seq)
+			}
+		}
+		slices.Sort(m.report.Lost)
+	}
+
+	return m.report
+}
+
+// Reads the heartbeats written by this measurement from the primary, and
+// then drops the heartbeat database.
+func (m *DowntimeMeasurement) loadHeartbeats(ctx context.Context) (map[int64]bool, error) {
+	db, err := sql.Open("mysql", RenderDSN(m.cfg, m.hostname, m.port))
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	rows, err := db.QueryContext(ctx, fmt.Sprintf("SELECT seq FROM `%s`.`heartbeat` WHERE seq >= ?", HeartbeatDatabase), m.first)
+	if err != nil {
+		return nil, fmt.Errorf("error reading heartbeats through %s: %w", m.hostname, err)
+	}
+	defer rows.Close()
+	present := make(map[int64]bool)
+	for rows.Next() {
+		var seq int64
+		err := rows.Scan(&seq)
+		if err != nil {
+			return nil, fmt.Errorf("error reading heartbeats through %s: %w", m.hostname, err)
+		}
+		present[seq] = true
+	}
+	if rows.Err() != nil {
+		return nil, fmt.Errorf("error reading heartbeats through %s: %w", m.hostname, rows.Err())
+	}
+
+	_, err = db.ExecContext(ctx, fmt.Sprintf("DROP DATABASE `%s`", HeartbeatDatabase))
+	if err != nil {
+		log.Printf("WARNING: error dropping heartbeat database %s: %v", HeartbeatDatabase, err)
+	}
+	return present, nil
+}
+
+// Returns the steps logged while writes were unavailable during |w|,
+// starting with the last step logged before it began.
+func StepsDuring(steps []LoggedStep, w DowntimeWindow) []LoggedStep {
+	var ret []LoggedStep
+	for i, step := range steps {
+		if step.At.After(w.End) {
+			break
+		}
+		if !step.At.Before(w.Start) {
+			ret = append(ret, step)
+		} else if i+1 == len(steps) || !steps[i+1].At.Before(w.Start) {
+			ret = append(ret, step)
+		}
+	}
+	return ret
+}
+
+// Writes |report| to |w|.
+func RenderDowntimeReport(w io.Writer, report DowntimeReport) {
+	const timeFormat = "15:04:05.000"
+
+	fmt.Fprintf(w, "heartbeats written: %d, acknowledged: %d", report.Written, report.Acknowledged)
+	if report.LostErr != nil {
+		fmt.Fprintf(w, ", lost: unknown (%v)\n", report.LostErr)
+	} else {
+		fmt.Fprintf(w, ", lost: %d\n", len(report.Lost))
+		for _, seq := range report.Lost {
+			fmt.Fprintf(w, "  lost heartbeat %d\n", seq)
+		}
+	}
+
+	if report.MaxDelayPod != "" {
+		fmt.Fprintf(w, "max replication delay: %v (%s)\n", report.MaxDelay.Round(time.Millisecond), report.MaxDelayPod)
+	} else {
+		fmt.Fprintln(w, "max replication delay: -")
+	}
+
+	var total time.Duration
+	for _, window := range report.Windows {
+		total += window.Duration()
+	}
+	fmt.Fprintf(w, "writes unavailable: %v in %d windows\n", total.Round(time.Millisecond), len(report.Windows))
+	for _, window := range report.Windows {
+		until := window.End.Format(timeFormat)
+		if window.Open {
+			until = "end"
+		}
+		fmt.Fprintf(w, "\n%v from %s to %s, %d failed writes, last error: %s\n", window.Duration().Round(time.Millisecond), window.Start.Format(timeFormat), until, window.FailedWrites, window.LastError)
+		for _, step := range StepsDuring(report.Steps, window) {
+			fmt.Fprintf(w, "  %s %s\n", step.At.Format(timeFormat), step.Line)
+		}
+	}
+}
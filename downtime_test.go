@@ -0,0 +1,98 @@
+// Copyright 2023 DoltHub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDowntimeMeasurementRecord(t *testing.T) {
+	start := time.Date(2023, 1, 2, 15, 4, 5, 0, time.UTC)
+	at := func(millis int) time.Time {
+		return start.Add(time.Duration(millis) * time.Millisecond)
+	}
+	m := &DowntimeMeasurement{first: 1, acked: make(map[int64]time.Time)}
+	m.recordWrite(1, at(0), at(10), nil)
+	m.recordWrite(2, at(100), at(1100), errors.New("read only"))
+	m.recordWrite(3, at(1200), at(1210), errors.New("connection refused"))
+	m.recordWrite(4, at(1300), at(1310), nil)
+	m.recordRead("dolt-1", 0, 4, at(1510))
+
+	assert.Equal(t, 4, m.report.Written)
+	assert.Equal(t, 2, m.report.Acknowledged)
+	assert.Equal(t, []DowntimeWindow{{
+		Start:        at(100),
+		End:          at(1310),
+		FailedWrites: 2,
+		LastError:    "connection refused",
+	}}, m.report.Windows)
+	assert.Equal(t, 1500*time.Millisecond, m.report.MaxDelay)
+	assert.Equal(t, "dolt-1", m.report.MaxDelayPod)
+}
+
+func TestStepsDuring(t *testing.T) {
+	start := time.Date(2023, 1, 2, 15, 4, 5, 0, time.UTC)
+	steps := []LoggedStep{
+		{At: start, Line: "labeled all pods as standby"},
+		{At: start.Add(time.Second), Line: "making dolt-0 standby"},
+		{At: start.Add(3 * time.Second), Line: "making dolt-1 primary"},
+		{At: start.Add(6 * time.Second), Line: "applied primary label to dolt-1"},
+	}
+	res := StepsDuring(steps, DowntimeWindow{Start: start.Add(2 * time.Second), End: start.Add(5 * time.Second)})
+	assert.Equal(t, steps[1:3], res)
+}
+
+func TestRenderDowntimeReport(t *testing.T) {
+	start := time.Date(2023, 1, 2, 15, 4, 5, 0, time.UTC)
+	var buf bytes.Buffer
+	RenderDowntimeReport(&buf, DowntimeReport{
+		Written:      20,
+		Acknowledged: 18,
+		Lost:         []int64{7},
+		Windows: []DowntimeWindow{{
+			Start:        start.Add(time.Second),
+			End:          start.Add(1250 * time.Millisecond),
+			FailedWrites: 2,
+			LastError:    "read only",
+		}},
+		MaxDelay:    250 * time.Millisecond,
+		MaxDelayPod: "dolt/dolt-1",
+		Steps: []LoggedStep{
+			{At: start, Line: "making dolt-0 standby"},
+			{At: start.Add(1100 * time.Millisecond), Line: "making dolt-1 primary"},
+			{At: start.Add(2 * time.Second), Line: "applied primary label to dolt-1"},
+		},
+	})
+	assert.Equal(t, `heartbeats written: 20, acknowledged: 18, lost: 1
+  lost heartbeat 7
+max replication delay: 250ms (dolt/dolt-1)
+writes unavailable: 250ms in 1 windows
+
+250ms from 15:04:06.000 to 15:04:06.250, 2 failed writes, last error: read only
+  15:04:05.000 making dolt-0 standby
+  15:04:06.100 making dolt-1 primary
+`, buf.String())
+}
+
+func TestHeartbeatDatabaseIsSystemDatabase(t *testing.T) {
+	assert.True(t, IsSystemDatabase(HeartbeatDatabase))
+	assert.True(t, IsSystemDatabase("dolt_cluster"))
+	assert.False(t, IsSystemDatabase("mydb"))
+}
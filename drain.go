@@ -0,0 +1,141 @@
+// Copyright 2023 DoltHub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+)
+
+// Drain moves the pods of the cluster off of the node given by -node. If
+// the primary is on the node, it first fails over to a standby on another
+// node. It then marks every pod on the node as wanting no traffic and
+// evicts them one at a time, waiting for each to be rescheduled and ready
+// before labeling it standby again.
+//
+// The node should be cordoned first, so that the pods are rescheduled
+// elsewhere.
+type Drain struct{}
+
+func (cmd Drain) Run(ctx context.Context, cfg *Config, cluster Cluster) error {
+	dbstates := LoadDBStates(ctx, cfg, cluster)
+
+	var affected []int
+	for i, state := range dbstates {
+		instance, ok := state.Instance.(EvictableInstance)
+		if !ok {
+			return fmt.Errorf("cannot drain %s: its pods cannot be evicted", cluster.Name())
+		}
+		if instance.NodeName() == cfg.Node {
+			affected = append(affected, i)
+		}
+	}
+	if len(affected) == 0 {
+		log.Printf("no pods of %s are on node %s", cluster.Name(), cfg.Node)
+		return nil
+	}
+
+	currentprimary, err := DrainPrimary(cfg, dbstates)
+	if err != nil {
+		return err
+	}
+	if currentprimary != -1 && slices.Contains(affected, currentprimary) {
+		target := -1
+		if cfg.FailoverTo != "" {
+			target, err = ResolveInstance(dbstates, cfg.FailoverTo)
+			if err != nil {
+				return fmt.Errorf("cannot drain %s: %w", cfg.Node, err)
+			}
+			if slices.Contains(affected, target) {
+				return fmt.Errorf("cannot drain %s: %s, given with -to, is on the same node", cfg.Node, dbstates[target].Instance.Name())
+			}
+		} else {
+			target = DrainFailoverTarget(dbstates, affected)
+			if target == -1 {
+				return fmt.Errorf("cannot drain %s: the primary, %s, is on it and no reachable standby is on another node", cfg.Node, dbstates[currentprimary].Instance.Name())
+			}
+		}
+
+		log.Printf("the primary, %s, is on node %s; failing over to %s", dbstates[currentprimary].Instance.Name(), cfg.Node, dbstates[target].Instance.Name())
+		failoverCfg := *cfg
+		failoverCfg.FailoverTo = dbstates[target].Instance.Name()
+		err = GracefulFailover{}.Run(ctx, &failoverCfg, cluster)
+		if err != nil {
+			return fmt.Errorf("cannot drain %s: %w", cfg.Node, err)
+		}
+	}
+
+	for _, i := range affected {
+		instance := dbstates[i].Instance
+		err := instance.MarkRoleUnknown(ctx)
+		if err != nil {
+			return err
+		}
+		log.Printf("removed role label from %s", instance.Name())
+	}
+
+	for _, i := range affected {
+		instance := dbstates[i].Instance.(EvictableInstance)
+
+		evictCtx, cancel := context.WithTimeout(ctx, cfg.WaitForReady)
+		err := instance.Evict(evictCtx)
+		if err == nil {
+			err = WaitForDBReady(evictCtx, cfg, instance)
+		}
+		cancel()
+		if err != nil {
+			return fmt.Errorf("error evicting %s from node %s: %w", instance.Name(), cfg.Node, err)
+		}
+
+		err = instance.MarkRoleStandby(ctx)
+		if err != nil {
+			return err
+		}
+		if instance.NodeName() == cfg.Node {
+			log.Printf("WARNING: %s was rescheduled onto node %s. Is it cordoned?", instance.Name(), cfg.Node)
+		}
+		log.Printf("pod is ready %s", instance.Name())
+	}
+
+	return nil
+}
+
+// Returns the current primary. If it cannot be found, draining could evict
+// it without failing over first, so it is an error unless -force is given,
+// in which case it returns -1.
+func DrainPrimary(cfg *Config, dbstates []DBState) (int, error) {
+	primary, _, err := CurrentPrimaryAndEpoch(dbstates)
+	if err == nil {
+		return primary, nil
+	}
+	if !cfg.Force {
+		return -1, fmt.Errorf("cannot drain %s: could not find the primary: %w. Run with -force to drain it anyway.", cfg.Node, err)
+	}
+	log.Printf("WARNING: could not find the primary: %v", err)
+	return -1, nil
+}
+
+// Returns the lowest-ordinal reachable standby which is not in |affected|,
+// or -1 if there is none.
+func DrainFailoverTarget(dbstates []DBState, affected []int) int {
+	for i, state := range dbstates {
+		if state.Err == nil && state.Role == "standby" && !slices.Contains(affected, i) {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,66 @@
+// Copyright 2023 DoltHub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDrainFailoverTarget(t *testing.T) {
+	dbstates := []DBState{{
+		Role:     "primary",
+		Instance: &mockInstance{name: "dolt-0"},
+	}, {
+		Role:     "standby",
+		Instance: &mockInstance{name: "dolt-1"},
+	}, {
+		Instance: &mockInstance{name: "dolt-2"},
+		Err:      errors.New("connection refused"),
+	}, {
+		Role:     "standby",
+		Instance: &mockInstance{name: "dolt-3"},
+	}}
+	assert.Equal(t, 1, DrainFailoverTarget(dbstates, []int{0}))
+	assert.Equal(t, 3, DrainFailoverTarget(dbstates, []int{0, 1}))
+	assert.Equal(t, -1, DrainFailoverTarget(dbstates, []int{0, 1, 3}))
+}
+
+func TestDrainPrimary(t *testing.T) {
+	dbstates := []DBState{{
+		Role:     "primary",
+		Epoch:    2,
+		Instance: &mockInstance{name: "dolt-0"},
+	}, {
+		Role:     "standby",
+		Epoch:    2,
+		Instance: &mockInstance{name: "dolt-1"},
+	}}
+	primary, err := DrainPrimary(&Config{Node: "node-a"}, dbstates)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, primary)
+
+	dbstates[0] = DBState{
+		Instance: &mockInstance{name: "dolt-0"},
+		Err:      errors.New("connection refused"),
+	}
+	_, err = DrainPrimary(&Config{Node: "node-a"}, dbstates)
+	assert.Error(t, err)
+	primary, err = DrainPrimary(&Config{Node: "node-a", Force: true}, dbstates)
+	assert.NoError(t, err)
+	assert.Equal(t, -1, primary)
+}
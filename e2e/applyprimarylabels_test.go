@@ -46,6 +46,22 @@ func TestApplyPrimaryLabels(t *testing.T) {
 		Assess("Connect/dolt-rw", RunUnitTestInCluster(InClusterTest{TestName: "TestConnectToService", DBName: "dolt-rw"})).
 		Assess("Connect/dolt-ro", RunUnitTestInCluster(InClusterTest{TestName: "TestConnectToService", DBName: "dolt-ro"})).
 		Feature()
+	markunknown := features.New("MarkUnknown").
+		WithSetup("create statefulset", CreateStatefulSet(WithReplicas(3))).
+		WithTeardown("delete statefulset", DeleteStatefulSet).
+		Assess("RunPrimaryLabels", RunDoltClusterCtlJob(WithArgs("-mark-unknown", "-mark-unknown-lag", "1s", "applyprimarylabels", "dolt"))).
+		Assess("dolt-1/IsStandby", AssertPodHasLabel("dolt-1", "dolthub.com/cluster_role", "standby")).
+		Assess("dolt-1/DisableRemotesAPI", RunUnitTestInCluster(InClusterTest{TestName: "TestDisableRemotesAPI", ToxiProxyEndpoint: "dolt-1.dolt-internal:8474"})).
+		Assess("CreateData", RunUnitTestInCluster(InClusterTest{TestName: "TestCreateSomeData", DBName: "dolt-rw"})).
+		Assess("WaitForLag", func(ctx context.Context, t *testing.T, c *envconf.Config) context.Context {
+			time.Sleep(2 * time.Second)
+			return ctx
+		}).
+		Assess("RunPrimaryLabels", RunDoltClusterCtlJob(WithArgs("-mark-unknown", "-mark-unknown-lag", "1s", "applyprimarylabels", "dolt"))).
+		Assess("dolt-0/IsPrimary", AssertPodHasLabel("dolt-0", "dolthub.com/cluster_role", "primary")).
+		Assess("dolt-1/IsUnknown", AssertPodDoesNotHaveLabel("dolt-1", "dolthub.com/cluster_role")).
+		Assess("dolt-2/IsStandby", AssertPodHasLabel("dolt-2", "dolthub.com/cluster_role", "standby")).
+		Feature()
 	password := features.New("WithPassword").
 		// Dolt 2.0 only supports a "root" superuser (configured via env vars on
 		// the dolt container); custom usernames are no longer settable through
@@ -84,12 +100,13 @@ func TestApplyPrimaryLabels(t *testing.T) {
 			ShouldFailWith("tls: failed to verify certificate: x509: certificate is valid for "))).
 		Feature()
 
-	testenv.Test(t, feature, password, tlsinsecureagainstplaintext, tlsinsecureagainsttlsloose, tlsca, wrongtlsservername)
+	testenv.Test(t, feature, markunknown, password, tlsinsecureagainstplaintext, tlsinsecureagainsttlsloose, tlsca, wrongtlsservername)
 }
 
 type DCCJob struct {
 	Args         []string
 	FailMatch    string
+	NotLogMatch  string
 	WithTLSRoots bool
 }
 
@@ -113,6 +130,13 @@ func ShouldFailWith(match string) DCCJobOption {
 	}
 }
 
+// The job has to succeed without logging |match|.
+func ShouldNotLog(match string) DCCJobOption {
+	return func(job *DCCJob) {
+		job.NotLogMatch = match
+	}
+}
+
 func RunDoltClusterCtlJob(opts ...DCCJobOption) features.Func {
 	var dccjob DCCJob
 	for _, o := range opts {
@@ -155,38 +179,13 @@ func RunDoltClusterCtlJob(opts ...DCCJobOption) features.Func {
 			t.Fatalf("expected job to fail with '%s' but the job succeeded", dccjob.FailMatch)
 		}
 
-		if !success {
-			// TODO: We need to assert on the fail match in the logs for the pod for the job.
-			podselector := job.Spec.Selector
-			var pods v1.PodList
-			err = client.Resources(c.Namespace()).List(context.TODO(), &pods, resources.WithLabelSelector(labels.Set(podselector.MatchLabels).String()))
-			if err != nil {
-				t.Fatalf("unable to list pods for failed job: %v", err)
-			}
-			if len(pods.Items) != 1 {
-				t.Fatalf("listing pods for failed job expected to find 1 pod, found: %d", len(pods.Items))
-			}
-			pod := pods.Items[0]
-
-			clientset, err := kubernetes.NewForConfig(client.RESTConfig())
-			if err != nil {
-				t.Fatalf("could not create client for retrieving pod logs: %v", err)
-			}
-			var podLogOpts v1.PodLogOptions
-			req := clientset.CoreV1().Pods(pod.GetNamespace()).GetLogs(pod.GetName(), &podLogOpts)
-			stream, err := req.Stream(context.TODO())
-			if err != nil {
-				t.Fatalf("error retreiving pod logs: %v", err)
-			}
-			defer stream.Close()
-			var contents bytes.Buffer
-			_, err = io.Copy(&contents, stream)
-			if err != nil {
-				t.Fatalf("error retreiving pod logs: %v", err)
-			}
-
-			contentsStr := contents.String()
-			if dccjob.FailMatch != "" {
+		if !success || dccjob.NotLogMatch != "" {
+			contentsStr := JobLogs(t, c, job)
+			if success {
+				if strings.Contains(contentsStr, dccjob.NotLogMatch) {
+					t.Fatalf("expected job not to log '%s' but it did:\n%s", dccjob.NotLogMatch, contentsStr)
+				}
+			} else if dccjob.FailMatch != "" {
 				if !strings.Contains(contentsStr, dccjob.FailMatch) {
 					t.Fatalf("failed to find expected match '%s' in pod logs:\n%s", dccjob.FailMatch, contentsStr)
 				}
@@ -199,6 +198,42 @@ func RunDoltClusterCtlJob(opts ...DCCJobOption) features.Func {
 	}
 }
 
+// Returns the logs of the pod of |job|.
+func JobLogs(t *testing.T, c *envconf.Config, job *batchv1.Job) string {
+	client, err := c.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	podselector := job.Spec.Selector
+	var pods v1.PodList
+	err = client.Resources(c.Namespace()).List(context.TODO(), &pods, resources.WithLabelSelector(labels.Set(podselector.MatchLabels).String()))
+	if err != nil {
+		t.Fatalf("unable to list pods for job: %v", err)
+	}
+	if len(pods.Items) != 1 {
+		t.Fatalf("listing pods for job expected to find 1 pod, found: %d", len(pods.Items))
+	}
+	pod := pods.Items[0]
+
+	clientset, err := kubernetes.NewForConfig(client.RESTConfig())
+	if err != nil {
+		t.Fatalf("could not create client for retrieving pod logs: %v", err)
+	}
+	var podLogOpts v1.PodLogOptions
+	req := clientset.CoreV1().Pods(pod.GetNamespace()).GetLogs(pod.GetName(), &podLogOpts)
+	stream, err := req.Stream(context.TODO())
+	if err != nil {
+		t.Fatalf("error retreiving pod logs: %v", err)
+	}
+	defer stream.Close()
+	var contents bytes.Buffer
+	_, err = io.Copy(&contents, stream)
+	if err != nil {
+		t.Fatalf("error retreiving pod logs: %v", err)
+	}
+	return contents.String()
+}
+
 func JobCompletedOrFailed(resources *resources.Resources, job k8s.Object, success *bool) apimachinerywait.ConditionWithContextFunc {
 	return func(ctx context.Context) (done bool, err error) {
 		if err := resources.Get(ctx, job.GetName(), job.GetNamespace(), job); err != nil {
@@ -294,3 +329,21 @@ func AssertPodHasLabel(name, key, value string) features.Func {
 		return ctx
 	}
 }
+
+func AssertPodDoesNotHaveLabel(name, key string) features.Func {
+	return func(ctx context.Context, t *testing.T, c *envconf.Config) context.Context {
+		client, err := c.NewClient()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var pod v1.Pod
+		err = client.Resources().Get(context.TODO(), name, c.Namespace(), &pod)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v, ok := pod.ObjectMeta.Labels[key]; ok {
+			t.Errorf("expected pod %v not to have label %v, instead had: %v", name, key, v)
+		}
+		return ctx
+	}
+}
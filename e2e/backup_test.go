@@ -0,0 +1,43 @@
+// Copyright 2023 DoltHub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"testing"
+
+	"sigs.k8s.io/e2e-framework/pkg/features"
+)
+
+func TestBackup(t *testing.T) {
+	newcluster := features.New("NewCluster").
+		WithSetup("create statefulset", CreateStatefulSet(WithReplicas(3))).
+		WithTeardown("delete statefulset", DeleteStatefulSet).
+		Assess("RunPrimaryLabels", RunDoltClusterCtlJob(WithArgs("applyprimarylabels", "dolt"))).
+		Assess("CreateData", RunUnitTestInCluster(InClusterTest{TestName: "TestCreateSomeData", DBName: "dolt-rw"})).
+		Assess("RunBackup", RunDoltClusterCtlJob(WithArgs("-backup-url", "file:///var/doltdb/backups", "backup", "dolt"))).
+		Feature()
+	behind := features.New("Behind").
+		WithSetup("create statefulset", CreateStatefulSet(WithReplicas(2))).
+		WithTeardown("delete statefulset", DeleteStatefulSet).
+		Assess("RunPrimaryLabels", RunDoltClusterCtlJob(WithArgs("applyprimarylabels", "dolt"))).
+		Assess("CreateData", RunUnitTestInCluster(InClusterTest{TestName: "TestCreateSomeData", DBName: "dolt-rw"})).
+		Assess("dolt-1/DisableRemotesAPI", RunUnitTestInCluster(InClusterTest{TestName: "TestDisableRemotesAPI", ToxiProxyEndpoint: "dolt-1.dolt-internal:8474"})).
+		Assess("CreateMoreData", RunUnitTestInCluster(InClusterTest{TestName: "TestCreateSomeMoreData", DBName: "dolt-rw"})).
+		Assess("RunBackup", RunDoltClusterCtlJob(
+			WithArgs("-backup-url", "file:///var/doltdb/backups", "-backup-max-lag", "0s", "backup", "dolt"),
+			ShouldFailWith("is not caught up"))).
+		Feature()
+	testenv.Test(t, newcluster, behind)
+}
@@ -0,0 +1,44 @@
+// Copyright 2023 DoltHub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"testing"
+
+	"sigs.k8s.io/e2e-framework/pkg/features"
+)
+
+func TestBootstrap(t *testing.T) {
+	fresh := features.New("Fresh").
+		WithSetup("create statefulset", CreateStatefulSet(WithReplicas(3))).
+		WithTeardown("delete statefulset", DeleteStatefulSet).
+		Assess("RunBootstrap", RunDoltClusterCtlJob(WithArgs("bootstrap", "dolt", "1"))).
+		Assess("dolt-1/IsPrimary", AssertPodHasLabel("dolt-1", "dolthub.com/cluster_role", "primary")).
+		Assess("dolt-0/IsStandby", AssertPodHasLabel("dolt-0", "dolthub.com/cluster_role", "standby")).
+		Assess("dolt-2/IsStandby", AssertPodHasLabel("dolt-2", "dolthub.com/cluster_role", "standby")).
+		Assess("CreateData", RunUnitTestInCluster(InClusterTest{TestName: "TestCreateSomeData", DBName: "dolt-rw"})).
+		Assess("AssertData", RunUnitTestInCluster(InClusterTest{TestName: "TestAssertCreatedDataPresent", DBName: "dolt-rw"})).
+		Feature()
+	notFresh := features.New("NotFresh").
+		WithSetup("create statefulset", CreateStatefulSet()).
+		WithTeardown("delete statefulset", DeleteStatefulSet).
+		Assess("RunPrimaryLabels", RunDoltClusterCtlJob(WithArgs("applyprimarylabels", "dolt"))).
+		Assess("RunGracefulFailover", RunDoltClusterCtlJob(WithArgs("gracefulfailover", "dolt"))).
+		Assess("RunBootstrap", RunDoltClusterCtlJob(
+			WithArgs("bootstrap", "dolt"),
+			ShouldFailWith("above -bootstrap-epoch 1"))).
+		Feature()
+	testenv.Test(t, fresh, notFresh)
+}
@@ -0,0 +1,37 @@
+// Copyright 2023 DoltHub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"testing"
+
+	"sigs.k8s.io/e2e-framework/pkg/features"
+)
+
+func TestCheck(t *testing.T) {
+	feature := features.New("NewCluster").
+		WithSetup("create statefulset", CreateStatefulSet()).
+		WithTeardown("delete statefulset", DeleteStatefulSet).
+		Assess("Unlabeled", RunDoltClusterCtlJob(
+			WithArgs("check", "dolt"),
+			ShouldFailWith("DOLTCLUSTER WARNING - pod"))).
+		Assess("RunPrimaryLabels", RunDoltClusterCtlJob(WithArgs("applyprimarylabels", "dolt"))).
+		Assess("Healthy", RunDoltClusterCtlJob(WithArgs("check", "dolt"))).
+		Assess("TooFewStandbys", RunDoltClusterCtlJob(
+			WithArgs("-require-standbys", "2", "check", "dolt"),
+			ShouldFailWith("DOLTCLUSTER CRITICAL - 1 pods are reachable in role standby, fewer than -require-standbys 2"))).
+		Feature()
+	testenv.Test(t, feature)
+}
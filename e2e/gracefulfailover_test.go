@@ -63,7 +63,26 @@ func TestGracefulFailover(t *testing.T) {
 		Assess("RunGracefulFailover", RunDoltClusterCtlJob(WithArgs("gracefulfailover", "dolt"))).
 		Assess("AssertData", RunUnitTestInCluster(InClusterTest{TestName: "TestAssertCreatedDataPresent", DBName: "dolt-rw"})).
 		Feature()
-	testenv.Test(t, newcluster, cycles, counts, preservesdata)
+	to := features.New("To").
+		WithSetup("create statefulset", CreateStatefulSet(WithReplicas(3))).
+		WithTeardown("delete statefulset", DeleteStatefulSet).
+		Assess("RunPrimaryLabels", RunDoltClusterCtlJob(WithArgs("applyprimarylabels", "dolt"))).
+		Assess("RunGracefulFailover", RunDoltClusterCtlJob(WithArgs("-to", "2", "gracefulfailover", "dolt"))).
+		Assess("dolt-0/IsStandby", AssertPodHasLabel("dolt-0", "dolthub.com/cluster_role", "standby")).
+		Assess("dolt-1/IsStandby", AssertPodHasLabel("dolt-1", "dolthub.com/cluster_role", "standby")).
+		Assess("dolt-2/IsPrimary", AssertPodHasLabel("dolt-2", "dolthub.com/cluster_role", "primary")).
+		Assess("Connect/dolt-rw", RunUnitTestInCluster(InClusterTest{TestName: "TestConnectToService", DBName: "dolt-rw"})).
+		Feature()
+	measuredowntime := features.New("MeasureDowntime").
+		WithSetup("create statefulset", CreateStatefulSet()).
+		WithTeardown("delete statefulset", DeleteStatefulSet).
+		Assess("RunPrimaryLabels", RunDoltClusterCtlJob(WithArgs("applyprimarylabels", "dolt"))).
+		Assess("CreateData", RunUnitTestInCluster(InClusterTest{TestName: "TestCreateSomeData", DBName: "dolt-rw"})).
+		Assess("RunGracefulFailover", RunDoltClusterCtlJob(WithArgs("-measure-downtime", "gracefulfailover", "dolt"))).
+		Assess("dolt-1/IsPrimary", AssertPodHasLabel("dolt-1", "dolthub.com/cluster_role", "primary")).
+		Assess("AssertData", RunUnitTestInCluster(InClusterTest{TestName: "TestAssertCreatedDataPresent", DBName: "dolt-rw"})).
+		Feature()
+	testenv.Test(t, newcluster, cycles, counts, preservesdata, to, measuredowntime)
 
 	t.Run("MinCaughtupStandbys", func(t *testing.T) {
 		// -min-caughtup-standbys fails early against 1.5.0
@@ -126,6 +145,23 @@ func TestGracefulFailover(t *testing.T) {
 			Assess("dolt-2/IsStandby", AssertPodHasLabel("dolt-2", "dolthub.com/cluster_role", "standby")).
 			Feature()
 
-		testenv.Test(t, against150, minTooHigh, worksOneOutOfThree, failsTwoOutOfThree)
+		// -to a standby which is not caught up fails and leaves dolt-0 primary.
+		toNotCaughtUp := features.New("ToNotCaughtUp").
+			WithSetup("create statefulset", CreateStatefulSet(WithReplicas(3))).
+			WithTeardown("delete statefulset", DeleteStatefulSet).
+			Assess("RunPrimaryLabels", RunDoltClusterCtlJob(WithArgs("applyprimarylabels", "dolt"))).
+			Assess("CreateData", RunUnitTestInCluster(InClusterTest{TestName: "TestCreateSomeData", DBName: "dolt-rw"})).
+			Assess("dolt-1/DisableRemotesAPI", RunUnitTestInCluster(InClusterTest{TestName: "TestDisableRemotesAPI", ToxiProxyEndpoint: "dolt-1.dolt-internal:8474"})).
+			Assess("CreateMoreData", RunUnitTestInCluster(InClusterTest{TestName: "TestCreateSomeMoreData", DBName: "dolt-rw"})).
+			Assess("RunGracefulFailover", RunDoltClusterCtlJob(
+				WithArgs("-timeout", "2m", "-min-caughtup-standbys", "1", "-to", "dolt-1", "gracefulfailover", "dolt"),
+				ShouldFailWith("it was not caught up by dolt_cluster_transition_to_standby"))).
+			Assess("AssertMoreData", RunUnitTestInCluster(InClusterTest{TestName: "TestAssertMoreCreatedDataPresent", DBName: "dolt-rw"})).
+			Assess("dolt-0/IsPrimary", AssertPodHasLabel("dolt-0", "dolthub.com/cluster_role", "primary")).
+			Assess("dolt-1/IsStandby", AssertPodHasLabel("dolt-1", "dolthub.com/cluster_role", "standby")).
+			Assess("dolt-2/IsStandby", AssertPodHasLabel("dolt-2", "dolthub.com/cluster_role", "standby")).
+			Feature()
+
+		testenv.Test(t, against150, minTooHigh, worksOneOutOfThree, failsTwoOutOfThree, toNotCaughtUp)
 	})
 }
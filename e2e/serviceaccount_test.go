@@ -30,10 +30,26 @@ func CreateDoltClusterCtlServiceAccount(ctx context.Context, c *envconf.Config)
 			APIGroups: []string{""},
 			Resources: []string{"pods"},
 			Verbs:     []string{"get", "update", "list", "watch", "delete"},
+		}, {
+			APIGroups: []string{""},
+			Resources: []string{"pods/eviction"},
+			Verbs:     []string{"create"},
+		}, {
+			APIGroups: []string{""},
+			Resources: []string{"pods/portforward"},
+			Verbs:     []string{"create"},
+		}, {
+			APIGroups: []string{""},
+			Resources: []string{"pods/exec"},
+			Verbs:     []string{"create"},
+		}, {
+			APIGroups: []string{""},
+			Resources: []string{"persistentvolumeclaims"},
+			Verbs:     []string{"get", "delete"},
 		}, {
 			APIGroups: []string{"apps"},
 			Resources: []string{"statefulsets"},
-			Verbs:     []string{"get", "list", "watch"},
+			Verbs:     []string{"get", "update", "list", "watch"},
 		}},
 	}
 	serviceaccount := &v1.ServiceAccount{
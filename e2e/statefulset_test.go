@@ -41,10 +41,22 @@ const ToxiProxyImage = "toxiproxy:latest"
 
 type StatefulSetConfig struct {
 	NumReplicas int32
-	Username    string
-	Password    string
-	TLSMode     TLSMode
-	ImageTag    string
+	// The number of servers the cluster config of every server lists,
+	// including those the StatefulSet does not have yet. Defaults to
+	// NumReplicas.
+	NumMembers int32
+	Username   string
+	Password   string
+	TLSMode    TLSMode
+	ImageTag   string
+	OnDelete   bool
+}
+
+func (config StatefulSetConfig) Members() int32 {
+	if config.NumMembers != 0 {
+		return config.NumMembers
+	}
+	return config.NumReplicas
 }
 
 // Context state which represents the configuration and created resources for
@@ -110,6 +122,12 @@ func WithReplicas(count int32) StatefulSetOption {
 	}
 }
 
+func WithMembers(count int32) StatefulSetOption {
+	return func(config *StatefulSetConfig) {
+		config.NumMembers = count
+	}
+}
+
 func WithCredentials(username, password string) StatefulSetOption {
 	return func(config *StatefulSetConfig) {
 		config.Username = username
@@ -129,6 +147,12 @@ func WithImageTag(tag string) StatefulSetOption {
 	}
 }
 
+func WithOnDeleteUpdateStrategy() StatefulSetOption {
+	return func(config *StatefulSetConfig) {
+		config.OnDelete = true
+	}
+}
+
 func CreateStatefulSet(opts ...StatefulSetOption) func(ctx context.Context, t *testing.T, c *envconf.Config) context.Context {
 	return func(ctx context.Context, t *testing.T, c *envconf.Config) context.Context {
 		var config StatefulSetConfig
@@ -182,6 +206,10 @@ func NewStatefulSet(namespace string, config StatefulSetConfig) (*appsv1.Statefu
 	if config.ImageTag != "" {
 		tag = config.ImageTag
 	}
+	var updateStrategy appsv1.StatefulSetUpdateStrategy
+	if config.OnDelete {
+		updateStrategy.Type = appsv1.OnDeleteStatefulSetStrategyType
+	}
 
 	// As of Dolt 2.0 the sql-server no longer reads users from the config file
 	// and only auto-creates a localhost-scoped root@localhost superuser. We use
@@ -211,6 +239,7 @@ func NewStatefulSet(namespace string, config StatefulSetConfig) (*appsv1.Statefu
 			Replicas:            &config.NumReplicas,
 			ServiceName:         "dolt-internal",
 			PodManagementPolicy: appsv1.ParallelPodManagement,
+			UpdateStrategy:      updateStrategy,
 			Template: v1.PodTemplateSpec{
 				ObjectMeta: metav1.ObjectMeta{Labels: labels},
 				Spec: v1.PodSpec{
@@ -369,7 +398,7 @@ func SqlServerConfig(this int32, config StatefulSetConfig) string {
 cluster:
   standby_remotes:
 `)
-	for i := int32(0); i < config.NumReplicas; i++ {
+	for i := int32(0); i < config.Members(); i++ {
 		if i != this {
 			parts = append(parts, StandbyRemoteStanza(i))
 		}
@@ -393,7 +422,7 @@ cluster:
 
 func SqlServerConfigMaps(name, namespace string, config StatefulSetConfig) []*v1.ConfigMap {
 	data := make(map[string]string)
-	for i := int32(0); i < config.NumReplicas; i++ {
+	for i := int32(0); i < config.Members(); i++ {
 		data[fmt.Sprintf("dolt-%d.yaml", i)] = SqlServerConfig(i, config)
 	}
 
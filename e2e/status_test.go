@@ -0,0 +1,54 @@
+// Copyright 2023 DoltHub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"testing"
+
+	"sigs.k8s.io/e2e-framework/pkg/features"
+)
+
+func TestStatus(t *testing.T) {
+	unlabeled := features.New("Unlabeled").
+		WithSetup("create statefulset", CreateStatefulSet()).
+		WithTeardown("delete statefulset", DeleteStatefulSet).
+		Assess("RunStatus", RunDoltClusterCtlJob(
+			WithArgs("status", "dolt"),
+			ShouldFailWith("is in role primary but is labeled unknown"))).
+		Feature()
+	labeled := features.New("Labeled").
+		WithSetup("create statefulset", CreateStatefulSet(WithReplicas(3))).
+		WithTeardown("delete statefulset", DeleteStatefulSet).
+		Assess("RunPrimaryLabels", RunDoltClusterCtlJob(WithArgs("applyprimarylabels", "dolt"))).
+		Assess("CreateData", RunUnitTestInCluster(InClusterTest{TestName: "TestCreateSomeData", DBName: "dolt-rw"})).
+		Assess("RunStatus", RunDoltClusterCtlJob(WithArgs("status", "dolt"))).
+		Feature()
+	portforward := features.New("PortForward").
+		WithSetup("create statefulset", CreateStatefulSet()).
+		WithTeardown("delete statefulset", DeleteStatefulSet).
+		Assess("RunPrimaryLabels", RunDoltClusterCtlJob(WithArgs("-connect-via", "port-forward", "applyprimarylabels", "dolt"))).
+		Assess("RunStatus", RunDoltClusterCtlJob(WithArgs("-connect-via", "port-forward", "status", "dolt"))).
+		Feature()
+	selector := features.New("Selector").
+		WithSetup("create statefulset", CreateStatefulSet()).
+		WithTeardown("delete statefulset", DeleteStatefulSet).
+		Assess("RunPrimaryLabels", RunDoltClusterCtlJob(WithArgs("-selector", "app=dolt", "applyprimarylabels", "dolt"))).
+		Assess("RunStatus", RunDoltClusterCtlJob(WithArgs("-selector", "app=dolt", "status", "dolt"))).
+		Assess("RunStatusNoPods", RunDoltClusterCtlJob(
+			WithArgs("-selector", "app=notdolt", "status", "dolt"),
+			ShouldFailWith("no pods match selector app=notdolt"))).
+		Feature()
+	testenv.Test(t, unlabeled, labeled, portforward, selector)
+}
@@ -121,7 +121,7 @@ func NewTLSBundle(namespace string, config StatefulSetConfig) (TLSBundle, error)
 		"dolt-rw." + namespace,
 		"dolt." + namespace,
 	}
-	for i := int32(0); i < config.NumReplicas; i++ {
+	for i := int32(0); i < config.Members(); i++ {
 		leafDNSNames = append(leafDNSNames, fmt.Sprintf("dolt-%d.dolt-internal.", i)+namespace)
 	}
 
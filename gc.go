@@ -0,0 +1,175 @@
+// Copyright 2023 DoltHub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"text/tabwriter"
+)
+
+// GC runs dolt_gc() on every server in the cluster without running it on
+// the primary. Like rollingrestart, it collects each standby in turn, from
+// the highest ordinal to the lowest, waiting for it to be healthy and for
+// every standby to be caught up before moving on. It then performs a
+// graceful failover and collects the old primary as a standby. Finally, it
+// reports the disk space reclaimed on each pod, if it can be measured.
+type GC struct{}
+
+func (cmd GC) Run(ctx context.Context, cfg *Config, cluster Cluster) error {
+	dbstates := LoadDBStates(ctx, cfg, cluster)
+
+	for _, state := range dbstates {
+		if state.Err != nil {
+			return fmt.Errorf("cannot perform rolling gc: %w", state.Err)
+		}
+		if state.Role == "detected_broken_config" {
+			return fmt.Errorf("cannot perform rolling gc: found pod %s in detected_broken_config", state.Instance.Name())
+		}
+	}
+
+	curprimary, _, err := CurrentPrimaryAndEpoch(dbstates)
+	if err != nil {
+		return fmt.Errorf("cannot perform rolling gc: %w", err)
+	}
+
+	before := make([]int64, len(dbstates))
+	for i, state := range dbstates {
+		before[i] = diskUsage(ctx, state.Instance)
+	}
+
+	for i := len(dbstates) - 1; i >= 0; i-- {
+		if i == curprimary {
+			continue
+		}
+		err := gcStandby(ctx, cfg, cluster, dbstates[i].Instance)
+		if err != nil {
+			return err
+		}
+	}
+
+	oldPrimary := dbstates[curprimary].Instance
+	log.Printf("failing over from %s so that it can be collected", oldPrimary.Name())
+	err = GracefulFailover{}.Run(ctx, cfg, cluster)
+	if err != nil {
+		return fmt.Errorf("cannot perform rolling gc: %w", err)
+	}
+
+	err = gcStandby(ctx, cfg, cluster, oldPrimary)
+	if err != nil {
+		return err
+	}
+
+	after := make([]int64, len(dbstates))
+	for i, state := range dbstates {
+		after[i] = diskUsage(ctx, state.Instance)
+	}
+	RenderReclaimed(os.Stdout, dbstates, before, after)
+
+	return nil
+}
+
+// Runs dolt_gc() in every database on |instance|, a standby, and waits up to
+// -wait-for-ready for it to be healthy and for every standby of |cluster| to
+// be caught up to within -max-lag.
+func gcStandby(ctx context.Context, cfg *Config, cluster Cluster, instance Instance) error {
+	names, err := LoadDatabaseNames(ctx, cfg, instance)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		err := CallGC(ctx, cfg, instance, name)
+		if err != nil {
+			return fmt.Errorf("error calling dolt_gc in database %s on %s: %w", name, instance.Name(), err)
+		}
+		log.Printf("called dolt_gc in database %s on %s", name, instance.Name())
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, cfg.WaitForReady)
+	defer cancel()
+	err = WaitForDBReady(waitCtx, cfg, instance)
+	if err != nil {
+		return err
+	}
+	state := LoadDBState(waitCtx, cfg, instance)
+	if state.Err != nil {
+		return state.Err
+	}
+	if state.Role != "standby" {
+		return fmt.Errorf("after dolt_gc, %s is in role %s, not standby", instance.Name(), state.Role)
+	}
+	_, err = WaitForStandbysCaughtUp(waitCtx, cfg, cluster, cfg.MaxLag)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("collected %s", instance.Name())
+	return nil
+}
+
+// Returns the disk usage of |instance|, or -1 if it cannot be measured.
+func diskUsage(ctx context.Context, instance Instance) int64 {
+	dui, ok := instance.(DiskUsageInstance)
+	if !ok {
+		return -1
+	}
+	used, err := dui.DiskUsage(ctx)
+	if err != nil {
+		log.Printf("WARNING: could not measure disk usage of %s: %v", instance.Name(), err)
+		return -1
+	}
+	return used
+}
+
+// Writes a table of the disk usage of each pod before and after collection
+// to |w|. Usage which could not be measured is -1.
+func RenderReclaimed(w io.Writer, dbstates []DBState, before, after []int64) {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "POD\tBEFORE\tAFTER\tRECLAIMED")
+	for i, state := range dbstates {
+		reclaimed := "-"
+		if before[i] != -1 && after[i] != -1 {
+			reclaimed = FormatBytes(before[i] - after[i])
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", state.Instance.Name(), FormatBytes(before[i]), FormatBytes(after[i]), reclaimed)
+	}
+	tw.Flush()
+}
+
+// Formats |n| bytes in the largest binary unit in which it is at least 1.
+// Returns "-" for -1.
+func FormatBytes(n int64) string {
+	if n == -1 {
+		return "-"
+	}
+	sign := ""
+	if n < 0 {
+		sign = "-"
+		n = -n
+	}
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%s%dB", sign, n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%s%.1f%ciB", sign, float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,42 @@
+// Copyright 2023 DoltHub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatBytes(t *testing.T) {
+	assert.Equal(t, "-", FormatBytes(-1))
+	assert.Equal(t, "512B", FormatBytes(512))
+	assert.Equal(t, "1.5KiB", FormatBytes(1536))
+	assert.Equal(t, "2.0GiB", FormatBytes(2<<30))
+	assert.Equal(t, "-3.0MiB", FormatBytes(-3<<20))
+}
+
+func TestRenderReclaimed(t *testing.T) {
+	var buf bytes.Buffer
+	RenderReclaimed(&buf, []DBState{
+		{Instance: &mockInstance{name: "dolt-0"}},
+		{Instance: &mockInstance{name: "dolt-1"}},
+	}, []int64{3 << 30, -1}, []int64{1 << 30, 5 << 20})
+	assert.Equal(t, `POD     BEFORE  AFTER   RECLAIMED
+dolt-0  3.0GiB  1.0GiB  2.0GiB
+dolt-1  -       5.0MiB  -
+`, buf.String())
+}
@@ -14,6 +14,7 @@ require (
 	k8s.io/client-go v0.36.2
 	sigs.k8s.io/e2e-framework v0.3.0
 	sigs.k8s.io/kind v0.24.0
+	sigs.k8s.io/yaml v1.6.0
 )
 
 require (
@@ -96,5 +97,4 @@ require (
 	sigs.k8s.io/json v0.0.0-20250730193827-2d320260d730 // indirect
 	sigs.k8s.io/randfill v1.0.0 // indirect
 	sigs.k8s.io/structured-merge-diff/v6 v6.4.0 // indirect
-	sigs.k8s.io/yaml v1.6.0 // indirect
 )
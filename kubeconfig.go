@@ -0,0 +1,52 @@
+// Copyright 2023 DoltHub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// Loads the configuration for talking to the Kubernetes API server.
+//
+// If neither kubeconfig nor context is given and we are running in a pod,
+// we use the pod's service account, as we always have. Otherwise we use
+// the standard kubeconfig loading rules, the same ones kubectl uses:
+// kubeconfig if it is given, and then $KUBECONFIG or ~/.kube/config,
+// selecting context if it is given and the current context otherwise.
+func KubernetesConfig(kubeconfig, context string) (*rest.Config, error) {
+	var inClusterErr error
+	if kubeconfig == "" && context == "" {
+		config, err := rest.InClusterConfig()
+		if err == nil {
+			return config, nil
+		}
+		inClusterErr = err
+	}
+
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = kubeconfig
+	overrides := &clientcmd.ConfigOverrides{CurrentContext: context}
+	config, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, overrides).ClientConfig()
+	if err != nil {
+		if inClusterErr != nil {
+			return nil, fmt.Errorf("not running in a kubernetes cluster (%v) and could not load a kubeconfig: %w", inClusterErr, err)
+		}
+		return nil, fmt.Errorf("could not load kubeconfig: %w", err)
+	}
+	return config, nil
+}
@@ -0,0 +1,82 @@
+// Copyright 2023 DoltHub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testKubeconfig = `apiVersion: v1
+kind: Config
+clusters:
+- name: laptop
+  cluster:
+    server: https://127.0.0.1:6443
+- name: ci
+  cluster:
+    server: https://ci.example.com:6443
+users:
+- name: admin
+  user:
+    token: not-a-real-token
+contexts:
+- name: laptop
+  context:
+    cluster: laptop
+    user: admin
+- name: ci
+  context:
+    cluster: ci
+    user: admin
+current-context: laptop
+`
+
+func TestKubernetesConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	assert.NoError(t, os.WriteFile(path, []byte(testKubeconfig), 0600))
+
+	t.Run("CurrentContext", func(t *testing.T) {
+		config, err := KubernetesConfig(path, "")
+		if assert.NoError(t, err) {
+			assert.Equal(t, "https://127.0.0.1:6443", config.Host)
+			assert.Equal(t, "not-a-real-token", config.BearerToken)
+		}
+	})
+	t.Run("Context", func(t *testing.T) {
+		config, err := KubernetesConfig(path, "ci")
+		if assert.NoError(t, err) {
+			assert.Equal(t, "https://ci.example.com:6443", config.Host)
+		}
+	})
+	t.Run("ContextFromKUBECONFIG", func(t *testing.T) {
+		t.Setenv("KUBECONFIG", path)
+		config, err := KubernetesConfig("", "ci")
+		if assert.NoError(t, err) {
+			assert.Equal(t, "https://ci.example.com:6443", config.Host)
+		}
+	})
+	t.Run("UnknownContext", func(t *testing.T) {
+		_, err := KubernetesConfig(path, "prod")
+		assert.ErrorContains(t, err, "prod")
+	})
+	t.Run("MissingKubeconfig", func(t *testing.T) {
+		_, err := KubernetesConfig(filepath.Join(t.TempDir(), "missing"), "")
+		assert.Error(t, err)
+	})
+}
@@ -15,18 +15,30 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"log"
+	"net/http"
+	"slices"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/cenkalti/backoff/v4"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/remotecommand"
 )
 
 const RoleLabel = "dolthub.com/cluster_role"
@@ -38,39 +50,268 @@ const StandbyRoleValue = "standby"
 type kubernetesCluster struct {
 	Namespace  string
 	ObjectName string
+	KubernetesClusterOptions
 
+	Config      *rest.Config
 	Clientset   *kubernetes.Clientset
 	StatefulSet *appsv1.StatefulSet
 	Pods        []*corev1.Pod
+
+	// The selector of the pods of the cluster, from Selector or the
+	// StatefulSet.
+	selector labels.Selector
+
+	// The pods which were left out when the cluster was last loaded,
+	// with SkipAbsent.
+	absent []string
 }
 
-func NewKubernetesCluster(ctx context.Context, namespace, objectname string, clientset *kubernetes.Clientset) (Cluster, error) {
+// How a kubernetesCluster finds and reaches its pods.
+type KubernetesClusterOptions struct {
+	// If non-nil, the sql-servers are reached through port-forwards
+	// instead of at their hostnames.
+	Tunnels *PortForwarder
+
+	// The label selector of the pods of the cluster. If empty, the
+	// selector of the StatefulSet, in which case every one of its
+	// replicas has to exist.
+	Selector string
+
+	// An annotation with an integer value by which the pods are
+	// ordered. If empty, they are ordered by the ordinal at the end of
+	// their names.
+	OrdinalAnnotation string
+
+	// The kubeconfig context the cluster was loaded from, if it is part
+	// of a compositeCluster spanning Kubernetes clusters. Prefixes the
+	// names of the cluster and its pods.
+	Context string
+
+	// If true, replicas of the StatefulSet which are missing and pods
+	// which are terminating are logged and left out of the cluster,
+	// instead of failing to load it. Long-running commands set it, as
+	// pods come and go while they run.
+	SkipAbsent bool
+}
+
+func NewKubernetesCluster(ctx context.Context, namespace, objectname string, config *rest.Config, clientset *kubernetes.Clientset, opts KubernetesClusterOptions) (Cluster, error) {
 	cluster := &kubernetesCluster{
-		Namespace:  namespace,
-		ObjectName: objectname,
-		Clientset:  clientset,
+		Namespace:                namespace,
+		ObjectName:               objectname,
+		KubernetesClusterOptions: opts,
+		Config:                   config,
+		Clientset:                clientset,
 	}
 
-	var err error
-	cluster.StatefulSet, err = clientset.AppsV1().StatefulSets(namespace).Get(ctx, objectname, metav1.GetOptions{})
+	err := cluster.Refresh(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("error loading StatefulSet %s/%s: %w", namespace, objectname, err)
+		return nil, err
 	}
 
-	cluster.Pods = make([]*corev1.Pod, cluster.NumReplicas())
-	for i := range cluster.Pods {
-		podname := objectname + "-" + strconv.Itoa(i)
-		cluster.Pods[i], err = clientset.CoreV1().Pods(namespace).Get(ctx, podname, metav1.GetOptions{})
-		if err != nil {
-			return nil, fmt.Errorf("error loading Pod %s/%s for StatefulSet %s/%s: %w", namespace, podname, namespace, objectname, err)
+	return cluster, nil
+}
+
+// Reloads the StatefulSet and its pods. If it fails, the cluster is left as
+// it was.
+func (kc *kubernetesCluster) Refresh(ctx context.Context) error {
+	apply, err := kc.stageRefresh(ctx)
+	if err != nil {
+		return err
+	}
+	apply()
+	return nil
+}
+
+// Loads the StatefulSet and its pods, and returns a function which makes
+// them the cluster's.
+func (kc *kubernetesCluster) stageRefresh(ctx context.Context) (func(), error) {
+	ss, err := kc.Clientset.AppsV1().StatefulSets(kc.Namespace).Get(ctx, kc.ObjectName, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error loading StatefulSet %s/%s: %w", kc.Namespace, kc.ObjectName, err)
+	}
+	loaded := &kubernetesCluster{
+		Namespace:                kc.Namespace,
+		ObjectName:               kc.ObjectName,
+		KubernetesClusterOptions: kc.KubernetesClusterOptions,
+		StatefulSet:              ss,
+	}
+
+	// Unless we were given a selector, the cluster is the replicas of
+	// the StatefulSet, and each of them has to exist.
+	var selector labels.Selector
+	var expected []string
+	if kc.Selector != "" {
+		selector, err = labels.Parse(kc.Selector)
+	} else {
+		selector, err = metav1.LabelSelectorAsSelector(ss.Spec.Selector)
+		expected = make([]string, loaded.replicas())
+		for i := range expected {
+			expected[i] = loaded.podName(i)
 		}
 	}
+	if err != nil {
+		return nil, fmt.Errorf("error parsing selector of StatefulSet %s: %w", kc.Name(), err)
+	}
 
-	return cluster, nil
+	pods, err := kc.Clientset.CoreV1().Pods(kc.Namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: selector.String(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing pods of StatefulSet %s: %w", kc.Name(), err)
+	}
+	ordered, absent, problems := OrderPods(pods.Items, kc.OrdinalAnnotation, expected)
+	if !kc.SkipAbsent {
+		problems = append(absent, problems...)
+	}
+	if len(ordered) == 0 && len(problems) == 0 {
+		problems = append(problems, fmt.Sprintf("no pods match selector %s", selector))
+	}
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("error loading pods of StatefulSet %s: %s", kc.Name(), strings.Join(problems, "; "))
+	}
+
+	return func() {
+		if len(absent) > 0 && !slices.Equal(absent, kc.absent) {
+			log.Printf("WARNING: leaving pods out of StatefulSet %s: %s", kc.Name(), strings.Join(absent, "; "))
+		}
+		kc.StatefulSet = ss
+		kc.selector = selector
+		kc.Pods = ordered
+		kc.absent = absent
+	}, nil
+}
+
+// Orders |pods| by their ordinals, which come from |annotation| if it is
+// non-empty and from the end of their names otherwise. If |expected| is
+// non-nil, it is the names of the pods which make up the cluster; other
+// pods are left out. Returns the ordered pods, then the pods which are
+// left out because they do not exist or are terminating, and then the
+// pods without a usable ordinal.
+func OrderPods(pods []corev1.Pod, annotation string, expected []string) ([]*corev1.Pod, []string, []string) {
+	var absent, problems []string
+	byName := make(map[string]*corev1.Pod)
+	for i := range pods {
+		byName[pods[i].Name] = &pods[i]
+	}
+
+	var members []*corev1.Pod
+	if expected != nil {
+		for _, name := range expected {
+			if p, ok := byName[name]; ok {
+				members = append(members, p)
+			} else {
+				absent = append(absent, fmt.Sprintf("pod %s is missing", name))
+			}
+		}
+	} else {
+		for i := range pods {
+			members = append(members, &pods[i])
+		}
+	}
+
+	ordinals := make(map[*corev1.Pod]int)
+	byOrdinal := make(map[int]*corev1.Pod)
+	var ordered []*corev1.Pod
+	for _, p := range members {
+		name := p.Namespace + "/" + p.Name
+		if p.DeletionTimestamp != nil {
+			absent = append(absent, fmt.Sprintf("pod %s is terminating", name))
+			continue
+		}
+		var ordinal int
+		var err error
+		if annotation != "" {
+			v, ok := p.Annotations[annotation]
+			if !ok {
+				problems = append(problems, fmt.Sprintf("pod %s has no annotation %s", name, annotation))
+				continue
+			}
+			ordinal, err = strconv.Atoi(v)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("pod %s has annotation %s=%s, which is not an integer", name, annotation, v))
+				continue
+			}
+		} else {
+			ordinal, err = strconv.Atoi(p.Name[strings.LastIndexByte(p.Name, '-')+1:])
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("pod %s has no ordinal at the end of its name", name))
+				continue
+			}
+		}
+		if other, ok := byOrdinal[ordinal]; ok {
+			problems = append(problems, fmt.Sprintf("pods %s/%s and %s both have ordinal %d", other.Namespace, other.Name, name, ordinal))
+			continue
+		}
+		byOrdinal[ordinal] = p
+		ordinals[p] = ordinal
+		ordered = append(ordered, p)
+	}
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return ordinals[ordered[i]] < ordinals[ordered[j]]
+	})
+	return ordered, absent, problems
+}
+
+// Watches the StatefulSet and the pods of the cluster. Watches
+// which are closed by the API server are reestablished until |ctx| is done.
+func (kc *kubernetesCluster) Watch(ctx context.Context) (<-chan struct{}, error) {
+	statefulsets := kc.Clientset.AppsV1().StatefulSets(kc.Namespace)
+	pods := kc.Clientset.CoreV1().Pods(kc.Namespace)
+
+	ch := make(chan struct{}, 1)
+	notify := func() {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+	watchLoop := func(what string, start func() (watch.Interface, error)) {
+		for ctx.Err() == nil {
+			w, err := start()
+			if err != nil {
+				log.Printf("WARNING: error watching %s: %v", what, err)
+				select {
+				case <-time.After(time.Second):
+				case <-ctx.Done():
+				}
+				continue
+			}
+			func() {
+				defer w.Stop()
+				for {
+					select {
+					case _, ok := <-w.ResultChan():
+						if !ok {
+							return
+						}
+						notify()
+					case <-ctx.Done():
+						return
+					}
+				}
+			}()
+		}
+	}
+
+	go watchLoop("StatefulSet "+kc.Name(), func() (watch.Interface, error) {
+		return statefulsets.Watch(ctx, metav1.ListOptions{
+			FieldSelector: fields.OneTermEqualSelector("metadata.name", kc.ObjectName).String(),
+		})
+	})
+	go watchLoop("pods of StatefulSet "+kc.Name(), func() (watch.Interface, error) {
+		return pods.Watch(ctx, metav1.ListOptions{
+			LabelSelector: kc.selector.String(),
+		})
+	})
+
+	return ch, nil
 }
 
 func (kc *kubernetesCluster) Name() string {
 	// TODO: From the statefulset metadata.
+	if kc.Context != "" {
+		return fmt.Sprintf("%s:%s/%s", kc.Context, kc.Namespace, kc.ObjectName)
+	}
 	return fmt.Sprintf("%s/%s", kc.Namespace, kc.ObjectName)
 }
 
@@ -79,12 +320,27 @@ func (kc *kubernetesCluster) ServiceName() string {
 }
 
 func (kc *kubernetesCluster) NumReplicas() int {
+	return len(kc.Pods)
+}
+
+// The spec.replicas of the StatefulSet.
+func (kc *kubernetesCluster) replicas() int {
 	if kc.StatefulSet.Spec.Replicas != nil {
 		return int(*kc.StatefulSet.Spec.Replicas)
 	}
 	return 1
 }
 
+// The name of the StatefulSet's |i|th replica, counting from
+// spec.ordinals.start.
+func (kc *kubernetesCluster) podName(i int) string {
+	start := 0
+	if kc.StatefulSet.Spec.Ordinals != nil {
+		start = int(kc.StatefulSet.Spec.Ordinals.Start)
+	}
+	return kc.ObjectName + "-" + strconv.Itoa(start+i)
+}
+
 func (kc *kubernetesCluster) Instance(i int) Instance {
 	return kubernetesClusterInstance{kc, i}
 }
@@ -102,6 +358,112 @@ func (kc *kubernetesCluster) port() int {
 	return 3306
 }
 
+// The image of the "dolt" container in |spec|, or "" if there is none.
+func doltImage(spec *corev1.PodSpec) string {
+	for _, c := range spec.Containers {
+		if c.Name == "dolt" {
+			return c.Image
+		}
+	}
+	return ""
+}
+
+func (kc *kubernetesCluster) Image() string {
+	return doltImage(&kc.StatefulSet.Spec.Template.Spec)
+}
+
+func (kc *kubernetesCluster) InstanceImage(i int) string {
+	return doltImage(&kc.Pods[i].Spec)
+}
+
+// Sets the image of the "dolt" container in the pod template, along with
+// that of every other container and init container which was running the
+// same image. The StatefulSet must use the OnDelete update strategy, so that
+// only doltclusterctl restarts its pods.
+func (kc *kubernetesCluster) SetImage(ctx context.Context, image string) error {
+	if kc.StatefulSet.Spec.UpdateStrategy.Type != appsv1.OnDeleteStatefulSetStrategyType {
+		return fmt.Errorf("StatefulSet %s must have spec.updateStrategy.type: OnDelete, so that changing its image does not restart its pods", kc.Name())
+	}
+	updated := kc.StatefulSet.DeepCopy()
+	spec := &updated.Spec.Template.Spec
+	old := doltImage(spec)
+	if old == "" {
+		return fmt.Errorf("StatefulSet %s has no container named dolt", kc.Name())
+	}
+	for i := range spec.Containers {
+		if spec.Containers[i].Image == old {
+			spec.Containers[i].Image = image
+		}
+	}
+	for i := range spec.InitContainers {
+		if spec.InitContainers[i].Image == old {
+			spec.InitContainers[i].Image = image
+		}
+	}
+	ss, err := kc.Clientset.AppsV1().StatefulSets(kc.Namespace).Update(ctx, updated, metav1.UpdateOptions{})
+	if err != nil {
+		return fmt.Errorf("error updating StatefulSet %s to image %s: %w", kc.Name(), image, err)
+	}
+	kc.StatefulSet = ss
+	return nil
+}
+
+func (kc *kubernetesCluster) MemberHostname(i int) string {
+	if i < len(kc.Pods) {
+		return kubernetesClusterInstance{kc, i}.Hostname()
+	}
+	return kc.podName(i) + "." + kc.ServiceName() + "." + kc.Namespace
+}
+
+// Sets spec.replicas of the StatefulSet. When scaling down, waits for the
+// StatefulSet controller to delete the removed pods. When scaling up, waits
+// for it to create the added pods and for them to be Ready.
+func (kc *kubernetesCluster) Scale(ctx context.Context, n int) error {
+	statefulsets := kc.Clientset.AppsV1().StatefulSets(kc.Namespace)
+	pods := kc.Clientset.CoreV1().Pods(kc.Namespace)
+
+	updated := kc.StatefulSet.DeepCopy()
+	replicas := int32(n)
+	updated.Spec.Replicas = &replicas
+	ss, err := statefulsets.Update(ctx, updated, metav1.UpdateOptions{})
+	if err != nil {
+		return fmt.Errorf("error updating StatefulSet %s to %d replicas: %w", kc.Name(), n, err)
+	}
+	kc.StatefulSet = ss
+	log.Printf("set replicas of StatefulSet %s to %d", kc.Name(), n)
+
+	for i := n; i < len(kc.Pods); i++ {
+		name := kc.Pods[i].Name
+		for {
+			_, err := pods.Get(ctx, name, metav1.GetOptions{})
+			if apierrors.IsNotFound(err) {
+				break
+			}
+			if ctx.Err() != nil {
+				return fmt.Errorf("error: pod %s/%s was not deleted after scaling down: %w", kc.Namespace, name, ctx.Err())
+			}
+			time.Sleep(100 * time.Millisecond)
+		}
+		log.Printf("pod %s/%s successfully deleted", kc.Namespace, name)
+	}
+	if n < len(kc.Pods) {
+		kc.Pods = kc.Pods[:n]
+	}
+
+	for i := len(kc.Pods); i < n; i++ {
+		kc.Pods = append(kc.Pods, &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: kc.podName(i), Namespace: kc.Namespace},
+		})
+		err := kubernetesClusterInstance{kc, i}.waitReady(ctx, "scaling up to")
+		if err != nil {
+			return err
+		}
+		log.Printf("pod %s/%s is ready", kc.Namespace, kc.Pods[i].Name)
+	}
+
+	return nil
+}
+
 type kubernetesClusterInstance struct {
 	cluster *kubernetesCluster
 	replica int
@@ -117,14 +479,30 @@ func (i kubernetesClusterInstance) Port() int {
 
 func (i kubernetesClusterInstance) Name() string {
 	p := i.pod()
+	if i.cluster.Context != "" {
+		return i.cluster.Context + ":" + p.Namespace + "/" + p.Name
+	}
 	return p.Namespace + "/" + p.Name
 }
 
+// The pod's DNS name in its headless service, from its spec.hostname and
+// spec.subdomain as the StatefulSet controller sets them, or from its name
+// and the StatefulSet's service if they are not set.
 func (i kubernetesClusterInstance) Hostname() string {
 	p := i.pod()
+	if p.Spec.Hostname != "" && p.Spec.Subdomain != "" {
+		return p.Spec.Hostname + "." + p.Spec.Subdomain + "." + p.Namespace
+	}
 	return p.Name + "." + i.cluster.ServiceName() + "." + p.Namespace
 }
 
+func (i kubernetesClusterInstance) ConnectAddress(ctx context.Context) (string, int, error) {
+	if i.cluster.Tunnels == nil {
+		return i.Hostname(), i.Port(), nil
+	}
+	return i.cluster.Tunnels.Address(ctx, i.pod(), i.Port())
+}
+
 func (i kubernetesClusterInstance) MarkRolePrimary(ctx context.Context) error {
 	p := i.pod()
 	if v, ok := p.ObjectMeta.Labels[RoleLabel]; ok && v == PrimaryRoleValue {
@@ -186,6 +564,146 @@ func (i kubernetesClusterInstance) Role() Role {
 }
 
 func (i kubernetesClusterInstance) Restart(ctx context.Context) error {
+	p := i.pod()
+	pods := i.cluster.Clientset.CoreV1().Pods(i.cluster.Namespace)
+	return i.recreate(ctx, "deleting", func() error {
+		return pods.Delete(ctx, p.Name, metav1.DeleteOptions{})
+	})
+}
+
+func (i kubernetesClusterInstance) NodeName() string {
+	return i.pod().Spec.NodeName
+}
+
+func (i kubernetesClusterInstance) Evict(ctx context.Context) error {
+	p := i.pod()
+	pods := i.cluster.Clientset.CoreV1().Pods(i.cluster.Namespace)
+	return i.recreate(ctx, "evicting", func() error {
+		return RetryEviction(ctx, i.Name(), evictionRetryInterval, func() error {
+			return pods.EvictV1(ctx, &policyv1.Eviction{
+				ObjectMeta: metav1.ObjectMeta{Name: p.Name, Namespace: p.Namespace},
+			})
+		})
+	})
+}
+
+const evictionRetryInterval = 5 * time.Second
+
+// Calls |evict| every |interval| for as long as it is refused with 429 Too
+// Many Requests, which is how the API server refuses an eviction that a
+// PodDisruptionBudget does not allow yet, until |ctx| is done.
+func RetryEviction(ctx context.Context, name string, interval time.Duration, evict func() error) error {
+	var last error
+	err := backoff.Retry(func() error {
+		err := evict()
+		if apierrors.IsTooManyRequests(err) {
+			log.Printf("eviction of pod %s is not allowed yet: %v", name, err)
+			last = err
+			return err
+		}
+		if err != nil {
+			return backoff.Permanent(err)
+		}
+		return nil
+	}, backoff.WithContext(backoff.NewConstantBackOff(interval), ctx))
+	if err != nil && last != nil && ctx.Err() != nil {
+		return fmt.Errorf("timed out waiting for the eviction of pod %s to be allowed: %w", name, last)
+	}
+	return err
+}
+
+// Returns the bytes used by the pod's PersistentVolumeClaim and emptyDir
+// volumes, according to du run in the container which mounts them. This
+// needs create on pods/exec, and du in the container's image.
+func (i kubernetesClusterInstance) DiskUsage(ctx context.Context) (int64, error) {
+	p := i.pod()
+	container, paths := diskUsagePaths(p)
+	if len(paths) == 0 {
+		return 0, fmt.Errorf("pod %s does not mount any PersistentVolumeClaim or emptyDir volumes", i.Name())
+	}
+	req := i.cluster.Clientset.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Namespace(p.Namespace).
+		Name(p.Name).
+		SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Container: container,
+			Command:   append([]string{"du", "-s", "-k"}, paths...),
+			Stdout:    true,
+			Stderr:    true,
+		}, scheme.ParameterCodec)
+	exec, err := remotecommand.NewSPDYExecutor(i.cluster.Config, http.MethodPost, req.URL())
+	if err != nil {
+		return 0, err
+	}
+	var stdout, stderr bytes.Buffer
+	err = exec.StreamWithContext(ctx, remotecommand.StreamOptions{Stdout: &stdout, Stderr: &stderr})
+	if err != nil {
+		return 0, fmt.Errorf("error running du in pod %s: %w: %s", i.Name(), err, strings.TrimSpace(stderr.String()))
+	}
+	used, err := ParseDiskUsage(stdout.String())
+	if err != nil {
+		return 0, fmt.Errorf("error running du in pod %s: %w", i.Name(), err)
+	}
+	return used, nil
+}
+
+// Returns the first container of |p| which mounts any of its
+// PersistentVolumeClaim or emptyDir volumes, along with the paths at which
+// it mounts them.
+func diskUsagePaths(p *corev1.Pod) (string, []string) {
+	storage := make(map[string]bool)
+	for _, v := range p.Spec.Volumes {
+		if v.PersistentVolumeClaim != nil || v.EmptyDir != nil {
+			storage[v.Name] = true
+		}
+	}
+	for _, c := range p.Spec.Containers {
+		var paths []string
+		for _, m := range c.VolumeMounts {
+			if storage[m.Name] {
+				paths = append(paths, m.MountPath)
+			}
+		}
+		if len(paths) > 0 {
+			return c.Name, paths
+		}
+	}
+	return "", nil
+}
+
+// Returns the total bytes in the output of du -s -k, which has a line of
+// kibibytes and a path for each path it measured.
+func ParseDiskUsage(output string) (int64, error) {
+	var used int64
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			return 0, fmt.Errorf("unexpected du output %q", output)
+		}
+		kib, err := strconv.ParseInt(fields[0], 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("unexpected du output %q", output)
+		}
+		used += kib * 1024
+	}
+	return used, nil
+}
+
+// Calls |remove| to remove the pod, waits for it to be deleted, and then
+// waits for the StatefulSet controller to recreate it and for it to be
+// Ready. |verb| describes |remove| in logs and errors.
+func (i kubernetesClusterInstance) recreate(ctx context.Context, verb string, remove func() error) error {
+	err := i.removeAndWait(ctx, verb, remove)
+	if err != nil {
+		return err
+	}
+	return i.waitReady(ctx, verb)
+}
+
+// Calls |remove| to remove the pod and waits for it to be deleted.
+func (i kubernetesClusterInstance) removeAndWait(ctx context.Context, verb string, remove func() error) error {
 	p := i.pod()
 	pods := i.cluster.Clientset.CoreV1().Pods(i.cluster.Namespace)
 	w, err := pods.Watch(ctx, metav1.ListOptions{
@@ -210,24 +728,30 @@ func (i kubernetesClusterInstance) Restart(ctx context.Context) error {
 			}
 		}
 	}()
-	log.Printf("deleting pod %s", i.Name())
-	err = pods.Delete(ctx, p.Name, metav1.DeleteOptions{})
+	log.Printf("%s pod %s", verb, i.Name())
+	err = remove()
 	if err != nil {
 		return err
 	}
 	<-done
 	log.Printf("pod %s successfully deleted", i.Name())
+	return nil
+}
 
+// Waits for the pod to exist and for all of its containers to be Ready.
+func (i kubernetesClusterInstance) waitReady(ctx context.Context, verb string) error {
+	p := i.pod()
+	pods := i.cluster.Clientset.CoreV1().Pods(i.cluster.Namespace)
 	pollInterval := 100 * time.Millisecond
 PollPod:
 	for {
 		time.Sleep(pollInterval)
 		if ctx.Err() != nil {
-			return fmt.Errorf("error: pod %s did not become Ready after deleting it: %w", i.Name(), ctx.Err())
+			return fmt.Errorf("error: pod %s did not become Ready after %s it: %w", i.Name(), verb, ctx.Err())
 		}
 
 		p, err := pods.Get(ctx, p.Name, metav1.GetOptions{})
-		if err != nil {
+		if err != nil || len(p.Status.ContainerStatuses) == 0 {
 			continue
 		}
 		for _, c := range p.Status.ContainerStatuses {
@@ -241,3 +765,67 @@ PollPod:
 		return nil
 	}
 }
+
+// Deletes the PersistentVolumeClaims of the pod along with the pod, so
+// that the StatefulSet controller recreates both from its
+// volumeClaimTemplates, and waits for the new pod to be Ready. Storage in
+// emptyDir volumes is deleted along with the pod.
+func (i kubernetesClusterInstance) Reseed(ctx context.Context) error {
+	p := i.pod()
+	pods := i.cluster.Clientset.CoreV1().Pods(i.cluster.Namespace)
+	pvcs := i.cluster.Clientset.CoreV1().PersistentVolumeClaims(i.cluster.Namespace)
+
+	var claims []string
+	for _, v := range p.Spec.Volumes {
+		if v.PersistentVolumeClaim != nil {
+			claims = append(claims, v.PersistentVolumeClaim.ClaimName)
+		}
+	}
+	for _, claim := range claims {
+		// The claim is not removed until the pod using it is deleted.
+		log.Printf("deleting PersistentVolumeClaim %s/%s", i.cluster.Namespace, claim)
+		err := pvcs.Delete(ctx, claim, metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("error deleting PersistentVolumeClaim %s of pod %s: %w", claim, i.Name(), err)
+		}
+	}
+
+	err := i.removeAndWait(ctx, "deleting", func() error {
+		return pods.Delete(ctx, p.Name, metav1.DeleteOptions{})
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(claims) > 0 {
+		for _, claim := range claims {
+			for {
+				_, err := pvcs.Get(ctx, claim, metav1.GetOptions{})
+				if apierrors.IsNotFound(err) {
+					break
+				}
+				if ctx.Err() != nil {
+					return fmt.Errorf("error: PersistentVolumeClaim %s/%s was not deleted: %w", i.cluster.Namespace, claim, ctx.Err())
+				}
+				time.Sleep(100 * time.Millisecond)
+			}
+			log.Printf("PersistentVolumeClaim %s/%s successfully deleted", i.cluster.Namespace, claim)
+		}
+
+		// A pod which was recreated while its old claims were
+		// still being deleted is stuck Pending. Deleting it again
+		// lets the controller create it along with new claims.
+		np, err := pods.Get(ctx, p.Name, metav1.GetOptions{})
+		if err == nil && np.Status.Phase == corev1.PodPending {
+			i.cluster.Pods[i.replica] = np
+			err = i.removeAndWait(ctx, "deleting pending", func() error {
+				return pods.Delete(ctx, p.Name, metav1.DeleteOptions{})
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return i.waitReady(ctx, "reseeding")
+}
@@ -0,0 +1,211 @@
+// Copyright 2023 DoltHub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func testPod(name string, annotations map[string]string) corev1.Pod {
+	return corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "dolt", Annotations: annotations}}
+}
+
+func podNames(pods []*corev1.Pod) []string {
+	var names []string
+	for _, p := range pods {
+		names = append(names, p.Name)
+	}
+	return names
+}
+
+func TestOrderPods(t *testing.T) {
+	t.Run("ByName", func(t *testing.T) {
+		pods := []corev1.Pod{testPod("dolt-10", nil), testPod("dolt-2", nil), testPod("dolt-1", nil)}
+		ordered, absent, problems := OrderPods(pods, "", nil)
+		assert.Empty(t, absent)
+		assert.Empty(t, problems)
+		assert.Equal(t, []string{"dolt-1", "dolt-2", "dolt-10"}, podNames(ordered))
+	})
+	t.Run("Expected", func(t *testing.T) {
+		// dolt-3 is left over from a scale down.
+		pods := []corev1.Pod{testPod("dolt-3", nil), testPod("dolt-1", nil), testPod("dolt-2", nil)}
+		ordered, absent, problems := OrderPods(pods, "", []string{"dolt-1", "dolt-2"})
+		assert.Empty(t, absent)
+		assert.Empty(t, problems)
+		assert.Equal(t, []string{"dolt-1", "dolt-2"}, podNames(ordered))
+	})
+	t.Run("Missing", func(t *testing.T) {
+		pods := []corev1.Pod{testPod("dolt-0", nil)}
+		ordered, absent, problems := OrderPods(pods, "", []string{"dolt-0", "dolt-1", "dolt-2"})
+		assert.Equal(t, []string{"pod dolt-1 is missing", "pod dolt-2 is missing"}, absent)
+		assert.Empty(t, problems)
+		assert.Equal(t, []string{"dolt-0"}, podNames(ordered))
+	})
+	t.Run("Terminating", func(t *testing.T) {
+		pods := []corev1.Pod{testPod("dolt-0", nil), testPod("dolt-1", nil)}
+		pods[1].DeletionTimestamp = &metav1.Time{}
+		ordered, absent, problems := OrderPods(pods, "", []string{"dolt-0", "dolt-1"})
+		assert.Equal(t, []string{"pod dolt/dolt-1 is terminating"}, absent)
+		assert.Empty(t, problems)
+		assert.Equal(t, []string{"dolt-0"}, podNames(ordered))
+	})
+	t.Run("NoOrdinal", func(t *testing.T) {
+		pods := []corev1.Pod{testPod("dolt-primary", nil)}
+		_, _, problems := OrderPods(pods, "", nil)
+		assert.Equal(t, []string{"pod dolt/dolt-primary has no ordinal at the end of its name"}, problems)
+	})
+	t.Run("DuplicateOrdinal", func(t *testing.T) {
+		pods := []corev1.Pod{testPod("dolt-0", nil), testPod("dolt-dr-0", nil)}
+		_, _, problems := OrderPods(pods, "", nil)
+		assert.Equal(t, []string{"pods dolt/dolt-0 and dolt/dolt-dr-0 both have ordinal 0"}, problems)
+	})
+	t.Run("Annotation", func(t *testing.T) {
+		pods := []corev1.Pod{
+			testPod("dolt-dr-0", map[string]string{"dolthub.com/ordinal": "2"}),
+			testPod("dolt-0", map[string]string{"dolthub.com/ordinal": "0"}),
+			testPod("dolt-1", map[string]string{"dolthub.com/ordinal": "1"}),
+		}
+		ordered, absent, problems := OrderPods(pods, "dolthub.com/ordinal", nil)
+		assert.Empty(t, absent)
+		assert.Empty(t, problems)
+		assert.Equal(t, []string{"dolt-0", "dolt-1", "dolt-dr-0"}, podNames(ordered))
+	})
+	t.Run("BadAnnotation", func(t *testing.T) {
+		pods := []corev1.Pod{
+			testPod("dolt-0", nil),
+			testPod("dolt-1", map[string]string{"dolthub.com/ordinal": "one"}),
+		}
+		_, _, problems := OrderPods(pods, "dolthub.com/ordinal", nil)
+		assert.Equal(t, []string{
+			"pod dolt/dolt-0 has no annotation dolthub.com/ordinal",
+			"pod dolt/dolt-1 has annotation dolthub.com/ordinal=one, which is not an integer",
+		}, problems)
+	})
+}
+
+func TestKubernetesClusterOrdinalsStart(t *testing.T) {
+	replicas := int32(2)
+	cluster := &kubernetesCluster{
+		Namespace:  "dolt",
+		ObjectName: "dolt",
+		StatefulSet: &appsv1.StatefulSet{Spec: appsv1.StatefulSetSpec{
+			Replicas:    &replicas,
+			ServiceName: "dolt-internal",
+			Ordinals:    &appsv1.StatefulSetOrdinals{Start: 5},
+		}},
+	}
+	pod := testPod("dolt-5", nil)
+	pod.Spec.Hostname = "dolt-5"
+	pod.Spec.Subdomain = "dolt-internal"
+	cluster.Pods = []*corev1.Pod{&pod}
+
+	assert.Equal(t, 1, cluster.NumReplicas())
+	assert.Equal(t, "dolt-5", cluster.podName(0))
+	assert.Equal(t, "dolt-5.dolt-internal.dolt", cluster.Instance(0).Hostname())
+	assert.Equal(t, "dolt-5.dolt-internal.dolt", cluster.MemberHostname(0))
+	assert.Equal(t, "dolt-6.dolt-internal.dolt", cluster.MemberHostname(1))
+
+	assert.Equal(t, "dolt/dolt", cluster.Name())
+	assert.Equal(t, "dolt/dolt-5", cluster.Instance(0).Name())
+	cluster.Context = "kind-dr"
+	assert.Equal(t, "kind-dr:dolt/dolt", cluster.Name())
+	assert.Equal(t, "kind-dr:dolt/dolt-5", cluster.Instance(0).Name())
+}
+
+func TestDiskUsagePaths(t *testing.T) {
+	pod := testPod("dolt-0", nil)
+	pod.Spec.Volumes = []corev1.Volume{{
+		Name:         "dolt-storage",
+		VolumeSource: corev1.VolumeSource{PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: "dolt-storage-dolt-0"}},
+	}, {
+		Name:         "dolt-config",
+		VolumeSource: corev1.VolumeSource{ConfigMap: &corev1.ConfigMapVolumeSource{}},
+	}, {
+		Name:         "scratch",
+		VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+	}}
+	pod.Spec.Containers = []corev1.Container{{
+		Name:         "toxiproxy",
+		VolumeMounts: []corev1.VolumeMount{{Name: "dolt-config", MountPath: "/etc/toxiproxy"}},
+	}, {
+		Name: "dolt",
+		VolumeMounts: []corev1.VolumeMount{
+			{Name: "dolt-storage", MountPath: "/var/doltdb"},
+			{Name: "dolt-config", MountPath: "/etc/dolt"},
+			{Name: "scratch", MountPath: "/tmp"},
+		},
+	}}
+	container, paths := diskUsagePaths(&pod)
+	assert.Equal(t, "dolt", container)
+	assert.Equal(t, []string{"/var/doltdb", "/tmp"}, paths)
+
+	pod.Spec.Containers = pod.Spec.Containers[:1]
+	_, paths = diskUsagePaths(&pod)
+	assert.Empty(t, paths)
+}
+
+func TestParseDiskUsage(t *testing.T) {
+	used, err := ParseDiskUsage("1024\t/var/doltdb\n8\t/tmp\n")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1032*1024), used)
+
+	_, err = ParseDiskUsage("du: cannot access '/var/doltdb': No such file or directory\n")
+	assert.Error(t, err)
+	_, err = ParseDiskUsage("")
+	assert.Error(t, err)
+}
+
+func TestRetryEviction(t *testing.T) {
+	disrupted := apierrors.NewTooManyRequests("Cannot evict pod as it would violate the pod's disruption budget.", 0)
+	t.Run("AllowedLater", func(t *testing.T) {
+		calls := 0
+		err := RetryEviction(context.Background(), "dolt/dolt-0", time.Millisecond, func() error {
+			calls++
+			if calls < 3 {
+				return disrupted
+			}
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, 3, calls)
+	})
+	t.Run("OtherError", func(t *testing.T) {
+		calls := 0
+		forbidden := errors.New("forbidden")
+		err := RetryEviction(context.Background(), "dolt/dolt-0", time.Millisecond, func() error {
+			calls++
+			return forbidden
+		})
+		assert.ErrorIs(t, err, forbidden)
+		assert.Equal(t, 1, calls)
+	})
+	t.Run("NeverAllowed", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		err := RetryEviction(ctx, "dolt/dolt-0", time.Millisecond, func() error {
+			return disrupted
+		})
+		assert.True(t, apierrors.IsTooManyRequests(err))
+	})
+}
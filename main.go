@@ -16,9 +16,13 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
+	"fmt"
 	"log"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/go-sql-driver/mysql"
@@ -31,30 +35,136 @@ func main() {
 	var cfg Config
 	cfg.Parse(flag.CommandLine, os.Args[1:])
 
-	ctx, f := context.WithDeadline(context.Background(), time.Now().Add(cfg.Timeout))
-	defer f()
+	// Runs before exiting, even when exiting with an error.
+	cleanup := func() {}
+
+	fatalf := func(format string, args ...any) {
+		log.Printf(format, args...)
+		cleanup()
+		if c, ok := cfg.Command.(FailureReportingCommand); ok {
+			os.Exit(c.ReportFailure(fmt.Errorf(format, args...)))
+		}
+		os.Exit(1)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	connect := func(context string) (*rest.Config, *kubernetes.Clientset) {
+		config, err := KubernetesConfig(cfg.Kubeconfig, context)
+		if err != nil {
+			fatalf("could not load kubernetes config: %v", err.Error())
+		}
+		clientset, err := kubernetes.NewForConfig(config)
+		if err != nil {
+			fatalf("could not build kubernetes client for config: %v", err.Error())
+		}
+		return config, clientset
+	}
+
+	if cfg.Remote {
+		// The remote doltclusterctl enforces -timeout itself, once its
+		// pod has started.
+		log.Printf("running %s against %s/%s in a pod in the cluster", cfg.CommandStr, cfg.Namespace, cfg.StatefulSetName)
+		_, clientset := connect(cfg.Context)
+		err := RunRemote(ctx, &cfg, clientset, RemoteArgs(&cfg, flag.CommandLine, os.Args[1:]), os.Stdout)
+		var exitErr *ExitError
+		if errors.As(err, &exitErr) {
+			log.Print(exitErr.Err)
+			os.Exit(exitErr.Code)
+		} else if err != nil {
+			fatalf("error running command remotely: %v", err.Error())
+		}
+		return
+	}
+
+	if _, ok := cfg.Command.(LongRunningCommand); !ok {
+		var f context.CancelFunc
+		ctx, f = context.WithDeadline(ctx, time.Now().Add(cfg.Timeout))
+		defer f()
+	}
 
 	if cfg.TLSConfig != nil {
 		mysql.RegisterTLSConfig("custom", cfg.TLSConfig)
 	}
 
+	if _, ok := cfg.Command.(StandaloneCommand); ok {
+		if err := cfg.Command.Run(ctx, &cfg, nil); err != nil {
+			fatalf("error running command: %v", err.Error())
+		}
+		return
+	}
+
 	log.Printf("running %s against %s/%s", cfg.CommandStr, cfg.Namespace, cfg.StatefulSetName)
 
-	config, err := rest.InClusterConfig()
-	if err != nil {
-		log.Fatalf("could not load kubernetes InClusterConfig: %v", err.Error())
+	var tunnels []*PortForwarder
+	cleanup = func() {
+		for _, t := range tunnels {
+			t.Close()
+		}
+	}
+	loadCluster := func(m ClusterMember) Cluster {
+		kubecontext := m.Context
+		if kubecontext == "" {
+			kubecontext = cfg.Context
+		}
+		config, clientset := connect(kubecontext)
+		// -selector and -ordinal-annotation cannot be given along
+		// with -member, so they only ever describe a lone StatefulSet.
+		opts := KubernetesClusterOptions{
+			Selector:          cfg.Selector,
+			OrdinalAnnotation: cfg.OrdinalAnnotation,
+			Context:           m.Context,
+		}
+		if _, ok := cfg.Command.(LongRunningCommand); ok {
+			opts.SkipAbsent = true
+		}
+		if cfg.ConnectVia == ConnectViaPortForward {
+			opts.Tunnels = NewPortForwarder(config, clientset)
+			tunnels = append(tunnels, opts.Tunnels)
+		}
+		cluster, err := NewKubernetesCluster(ctx, m.Namespace, m.StatefulSet, config, clientset, opts)
+		if err != nil {
+			fatalf("could not load stateful set %s and its pods: %v", m, err.Error())
+		}
+		return cluster
+	}
+
+	cluster := loadCluster(ClusterMember{Namespace: cfg.Namespace, StatefulSet: cfg.StatefulSetName})
+	if len(cfg.Members) > 0 {
+		members := []Cluster{cluster}
+		for _, m := range cfg.Members {
+			members = append(members, loadCluster(m))
+		}
+		cluster = NewCompositeCluster(members)
+		log.Printf("cluster %s has %d replicas", cluster.Name(), cluster.NumReplicas())
 	}
-	clientset, err := kubernetes.NewForConfig(config)
-	if err != nil {
-		log.Fatalf("could not build kubernetes client for config: %v", err.Error())
+
+	var measurement *DowntimeMeasurement
+	if cfg.MeasureDowntime {
+		var err error
+		measurement, err = StartDowntimeMeasurement(ctx, &cfg, cluster)
+		if err != nil {
+			fatalf("could not start measuring downtime: %v", err.Error())
+		}
 	}
 
-	cluster, err := NewKubernetesCluster(ctx, cfg.Namespace, cfg.StatefulSetName, clientset)
-	if err != nil {
-		log.Fatalf("could not load stateful set %s/%s and its pods: %v", cfg.Namespace, cfg.StatefulSetName, err.Error())
+	err := cfg.Command.Run(ctx, &cfg, cluster)
+
+	if measurement != nil {
+		// The measurement has to read the heartbeats back even if the
+		// command ran out of time.
+		stopCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		RenderDowntimeReport(os.Stdout, measurement.Stop(stopCtx))
+		cancel()
 	}
 
-	if err := cfg.Command.Run(ctx, &cfg, cluster); err != nil {
-		log.Fatalf("error running command: %v", err.Error())
+	var exitErr *ExitError
+	if errors.As(err, &exitErr) {
+		cleanup()
+		os.Exit(exitErr.Code)
+	} else if err != nil {
+		fatalf("error running command: %v", err.Error())
 	}
+	cleanup()
 }
@@ -17,10 +17,13 @@ package main
 import (
 	"context"
 	"crypto/tls"
+	"crypto/x509"
 	"fmt"
+	"net/url"
 	"os"
 	"testing"
 
+	"github.com/go-sql-driver/mysql"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -40,6 +43,47 @@ func (c mockCluster) Instance(i int) Instance {
 	return nil
 }
 
+type mockInstance struct {
+	name     string
+	hostname string
+	role     Role
+}
+
+func (i *mockInstance) Name() string {
+	return i.name
+}
+
+func (i *mockInstance) Hostname() string {
+	return i.hostname
+}
+
+func (i *mockInstance) Port() int {
+	return 3306
+}
+
+func (i *mockInstance) Role() Role {
+	return i.role
+}
+
+func (i *mockInstance) MarkRolePrimary(context.Context) error {
+	i.role = RolePrimary
+	return nil
+}
+
+func (i *mockInstance) MarkRoleStandby(context.Context) error {
+	i.role = RoleStandby
+	return nil
+}
+
+func (i *mockInstance) MarkRoleUnknown(context.Context) error {
+	i.role = RoleUnknown
+	return nil
+}
+
+func (i *mockInstance) Restart(context.Context) error {
+	return nil
+}
+
 func TestLoadDBStates(t *testing.T) {
 	t.Run("ZeroReplicas", func(t *testing.T) {
 		res := LoadDBStates(context.Background(), &Config{}, mockCluster{0})
@@ -89,4 +133,72 @@ func TestRenderDSN(t *testing.T) {
 		res := RenderDSN(&Config{TLSConfig: &tls.Config{}}, "localhost", 3306)
 		assert.Equal(t, "root@tcp(localhost:3306)/dolt_cluster?parseTime=true&tls=custom", res)
 	})
+	t.Run("Tunneled", func(t *testing.T) {
+		res := RenderTunneledDSN(&Config{}, "127.0.0.1", 40000, "dolt-0.dolt-internal.dolt")
+		assert.Equal(t, "root@tcp(127.0.0.1:40000)/dolt_cluster?parseTime=true", res)
+	})
+	t.Run("TunneledTLSVerified", func(t *testing.T) {
+		res := RenderTunneledDSN(&Config{TLSVerified: true}, "127.0.0.1", 40000, "dolt-0.dolt-internal.dolt")
+		assert.Equal(t, "root@tcp(127.0.0.1:40000)/dolt_cluster?parseTime=true&tls=tunnel-dolt-0.dolt-internal.dolt", res)
+		parsed, err := mysql.ParseDSN(res)
+		assert.NoError(t, err)
+		assert.Equal(t, "dolt-0.dolt-internal.dolt", parsed.TLS.ServerName)
+	})
+	t.Run("TunneledTLSConfig", func(t *testing.T) {
+		roots := x509.NewCertPool()
+		res := RenderTunneledDSN(&Config{TLSConfig: &tls.Config{RootCAs: roots}}, "127.0.0.1", 40000, "dolt-1.dolt-internal.dolt")
+		parsed, err := mysql.ParseDSN(res)
+		assert.NoError(t, err)
+		assert.Equal(t, "dolt-1.dolt-internal.dolt", parsed.TLS.ServerName)
+		assert.Same(t, roots, parsed.TLS.RootCAs)
+	})
+	t.Run("TunneledTLSServerName", func(t *testing.T) {
+		res := RenderTunneledDSN(&Config{TLSConfig: &tls.Config{ServerName: "dolt.example.com"}}, "127.0.0.1", 40000, "dolt-0.dolt-internal.dolt")
+		assert.Equal(t, "root@tcp(127.0.0.1:40000)/dolt_cluster?parseTime=true&tls=custom", res)
+	})
+}
+
+func TestCaughtUpStandbys(t *testing.T) {
+	dbstates := []DBState{{
+		Instance: &mockInstance{name: "dolt-0", hostname: "dolt-0.dolt-internal.default"},
+	}, {
+		Instance: &mockInstance{name: "dolt-1", hostname: "dolt-1.dolt-internal.default"},
+	}, {
+		Instance: &mockInstance{name: "dolt-2", hostname: "dolt-2.dolt-internal.default"},
+	}}
+	result := func(caughtUp int, db, remote string) TransitionResult {
+		res := TransitionResult{
+			CaughtUp:  caughtUp,
+			Database:  db,
+			Remote:    remote,
+			RemoteURL: "http://" + remote + ".dolt-internal:50051/" + db,
+		}
+		res.Parsed, _ = url.Parse(res.RemoteURL)
+		return res
+	}
+	t.Run("AllCaughtUp", func(t *testing.T) {
+		res := CaughtUpStandbys([]TransitionResult{
+			result(1, "one", "dolt-1"),
+			result(1, "one", "dolt-2"),
+			result(1, "two", "dolt-1"),
+			result(1, "two", "dolt-2"),
+		}, dbstates)
+		assert.Equal(t, []int{1, 2}, res)
+	})
+	t.Run("OneDatabaseBehind", func(t *testing.T) {
+		res := CaughtUpStandbys([]TransitionResult{
+			result(1, "one", "dolt-1"),
+			result(1, "one", "dolt-2"),
+			result(0, "two", "dolt-1"),
+			result(1, "two", "dolt-2"),
+		}, dbstates)
+		assert.Equal(t, []int{2}, res)
+	})
+	t.Run("NoneCaughtUp", func(t *testing.T) {
+		res := CaughtUpStandbys([]TransitionResult{
+			result(0, "one", "dolt-1"),
+			result(0, "one", "dolt-2"),
+		}, dbstates)
+		assert.Len(t, res, 0)
+	})
 }
@@ -0,0 +1,336 @@
+// Copyright 2023 DoltHub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/yaml"
+)
+
+const ManifestsDefaultImage = "dolthub/dolt:latest"
+
+const sqlServerPort = 3306
+const remotesAPIPort = 50051
+
+// Manifests writes the Kubernetes objects for a complete dolt cluster named
+// after the StatefulSet to stdout, as YAML which kubectl apply accepts. It
+// does not connect to Kubernetes.
+type Manifests struct{}
+
+func (cmd Manifests) Standalone() {}
+
+func (cmd Manifests) Run(ctx context.Context, cfg *Config, cluster Cluster) error {
+	objs, err := ClusterManifests(cfg)
+	if err != nil {
+		return err
+	}
+	return WriteManifests(os.Stdout, objs)
+}
+
+// Writes |objs| to |w| as a stream of YAML documents.
+func WriteManifests(w io.Writer, objs []runtime.Object) error {
+	for i, obj := range objs {
+		bs, err := yaml.Marshal(obj)
+		if err != nil {
+			return fmt.Errorf("error rendering %T as YAML: %w", obj, err)
+		}
+		if i != 0 {
+			fmt.Fprintln(w, "---")
+		}
+		_, err = w.Write(bs)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Returns the objects for a dolt cluster named cfg.StatefulSetName in
+// cfg.Namespace. They follow the conventions kubernetesCluster relies on:
+// pods are named after the StatefulSet and their ordinal, are reachable at
+// their hostname under its headless service, run the sql-server in a
+// container named dolt with a port named dolt, and are routed traffic by
+// their dolthub.com/cluster_role label.
+func ClusterManifests(cfg *Config) ([]runtime.Object, error) {
+	name, namespace := cfg.StatefulSetName, cfg.Namespace
+	if cfg.Replicas < 2 {
+		return nil, errors.New("a dolt cluster needs at least 2 -replicas")
+	}
+	storage, err := resource.ParseQuantity(cfg.StorageSize)
+	if err != nil {
+		return nil, fmt.Errorf("invalid -storage-size %s: %w", cfg.StorageSize, err)
+	}
+	image := cfg.Image
+	if image == "" {
+		image = ManifestsDefaultImage
+	}
+
+	objs := manifestsRBAC(namespace)
+	objs = append(objs,
+		manifestsService(name+"-internal", namespace, name, ""),
+		manifestsService(name+"-rw", namespace, name, PrimaryRoleValue),
+		manifestsService(name+"-ro", namespace, name, StandbyRoleValue),
+	)
+
+	configs := make(map[string]string)
+	for i := 0; i < cfg.Replicas; i++ {
+		configs[fmt.Sprintf("%s-%d.yaml", name, i)] = SqlServerConfig(cfg, i)
+	}
+	objs = append(objs, &corev1.ConfigMap{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Data:       configs,
+	})
+
+	objs = append(objs, manifestsStatefulSet(cfg, image, storage))
+	return objs, nil
+}
+
+// The Role, ServiceAccount and RoleBinding with everything doltclusterctl
+// needs to run in |namespace| as the doltclusterctl service account.
+func manifestsRBAC(namespace string) []runtime.Object {
+	meta := metav1.ObjectMeta{Name: "doltclusterctl", Namespace: namespace}
+	return []runtime.Object{&rbacv1.Role{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "rbac.authorization.k8s.io/v1", Kind: "Role"},
+		ObjectMeta: meta,
+		Rules: []rbacv1.PolicyRule{{
+			APIGroups: []string{""},
+			Resources: []string{"pods"},
+			Verbs:     []string{"get", "update", "list", "watch", "delete"},
+		}, {
+			APIGroups: []string{""},
+			Resources: []string{"pods/eviction"},
+			Verbs:     []string{"create"},
+		}, {
+			APIGroups: []string{""},
+			Resources: []string{"pods/portforward"},
+			Verbs:     []string{"create"},
+		}, {
+			APIGroups: []string{""},
+			Resources: []string{"pods/exec"},
+			Verbs:     []string{"create"},
+		}, {
+			APIGroups: []string{""},
+			Resources: []string{"persistentvolumeclaims"},
+			Verbs:     []string{"get", "delete"},
+		}, {
+			APIGroups: []string{"apps"},
+			Resources: []string{"statefulsets"},
+			Verbs:     []string{"get", "update", "list", "watch"},
+		}},
+	}, &corev1.ServiceAccount{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ServiceAccount"},
+		ObjectMeta: meta,
+	}, &rbacv1.RoleBinding{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "rbac.authorization.k8s.io/v1", Kind: "RoleBinding"},
+		ObjectMeta: meta,
+		Subjects: []rbacv1.Subject{{
+			Kind: "ServiceAccount",
+			Name: "doltclusterctl",
+		}},
+		RoleRef: rbacv1.RoleRef{
+			APIGroup: "rbac.authorization.k8s.io",
+			Kind:     "Role",
+			Name:     "doltclusterctl",
+		},
+	}}
+}
+
+// A service selecting the pods of the cluster |app| with the given
+// dolthub.com/cluster_role label. With no role, a headless service
+// selecting every pod.
+func manifestsService(name, namespace, app, role string) *corev1.Service {
+	selector := map[string]string{"app": app}
+	if role != "" {
+		selector[RoleLabel] = role
+	}
+	svc := &corev1.Service{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Service"},
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Spec: corev1.ServiceSpec{
+			Selector: selector,
+			Ports: []corev1.ServicePort{{
+				Name:       "dolt",
+				Port:       sqlServerPort,
+				TargetPort: intstr.FromString("dolt"),
+			}},
+		},
+	}
+	if role == "" {
+		svc.Spec.ClusterIP = corev1.ClusterIPNone
+		svc.Spec.PublishNotReadyAddresses = true
+		svc.Spec.Ports = append(svc.Spec.Ports, corev1.ServicePort{
+			Name:       "remotesapi",
+			Port:       remotesAPIPort,
+			TargetPort: intstr.FromString("remotesapi"),
+		})
+	}
+	return svc
+}
+
+func manifestsStatefulSet(cfg *Config, image string, storage resource.Quantity) *appsv1.StatefulSet {
+	name := cfg.StatefulSetName
+	labels := map[string]string{"app": name}
+	replicas := int32(cfg.Replicas)
+
+	env := []corev1.EnvVar{{
+		Name:  "DOLT_ROOT_PATH",
+		Value: "/var/doltdb",
+	}, {
+		Name:  "DOLT_ROOT_HOST",
+		Value: "%",
+	}, {
+		Name: "DOLT_ROOT_PASSWORD",
+		ValueFrom: &corev1.EnvVarSource{
+			SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: name + "-credentials"},
+				Key:                  "password",
+				Optional:             func() *bool { b := true; return &b }(),
+			},
+		},
+	}}
+	mounts := []corev1.VolumeMount{{
+		Name:      "dolt-storage",
+		MountPath: "/var/doltdb",
+	}}
+	volumes := []corev1.Volume{{
+		Name: "dolt-config",
+		VolumeSource: corev1.VolumeSource{
+			ConfigMap: &corev1.ConfigMapVolumeSource{
+				LocalObjectReference: corev1.LocalObjectReference{Name: name},
+			},
+		},
+	}}
+	if cfg.ServerTLSSecret != "" {
+		mounts = append(mounts, corev1.VolumeMount{
+			Name:      "dolt-tls",
+			MountPath: "/etc/dolt-tls",
+			ReadOnly:  true,
+		})
+		volumes = append(volumes, corev1.Volume{
+			Name: "dolt-tls",
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{SecretName: cfg.ServerTLSSecret},
+			},
+		})
+	}
+
+	return &appsv1.StatefulSet{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "apps/v1", Kind: "StatefulSet"},
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: cfg.Namespace},
+		Spec: appsv1.StatefulSetSpec{
+			Selector:            &metav1.LabelSelector{MatchLabels: labels},
+			Replicas:            &replicas,
+			ServiceName:         name + "-internal",
+			PodManagementPolicy: appsv1.ParallelPodManagement,
+			// doltclusterctl upgrade and rollingrestart restart the pods
+			// themselves, failing over the primary first.
+			UpdateStrategy: appsv1.StatefulSetUpdateStrategy{Type: appsv1.OnDeleteStatefulSetStrategyType},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{
+						Name:    "dolt",
+						Image:   image,
+						Command: []string{"dolt", "sql-server", "--config", "config.yaml"},
+						Ports: []corev1.ContainerPort{{
+							Name:          "dolt",
+							ContainerPort: sqlServerPort,
+						}, {
+							Name:          "remotesapi",
+							ContainerPort: remotesAPIPort,
+						}},
+						WorkingDir:   "/var/doltdb",
+						Env:          env,
+						VolumeMounts: mounts,
+						ReadinessProbe: &corev1.Probe{
+							ProbeHandler: corev1.ProbeHandler{
+								TCPSocket: &corev1.TCPSocketAction{Port: intstr.FromString("dolt")},
+							},
+						},
+					}},
+					InitContainers: []corev1.Container{{
+						Name:    "init-dolt",
+						Image:   image,
+						Command: []string{"/bin/sh", "-c", `cp /etc/dolt/"${POD_NAME}".yaml /var/doltdb/config.yaml`},
+						Env: []corev1.EnvVar{{
+							Name: "POD_NAME",
+							ValueFrom: &corev1.EnvVarSource{
+								FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.name"},
+							},
+						}},
+						VolumeMounts: []corev1.VolumeMount{{
+							Name:      "dolt-storage",
+							MountPath: "/var/doltdb",
+						}, {
+							Name:      "dolt-config",
+							MountPath: "/etc/dolt",
+						}},
+					}},
+					Volumes: volumes,
+				},
+			},
+			VolumeClaimTemplates: []corev1.PersistentVolumeClaim{{
+				ObjectMeta: metav1.ObjectMeta{Name: "dolt-storage"},
+				Spec: corev1.PersistentVolumeClaimSpec{
+					AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+					Resources: corev1.VolumeResourceRequirements{
+						Requests: corev1.ResourceList{corev1.ResourceStorage: storage},
+					},
+				},
+			}},
+		},
+	}
+}
+
+// The config.yaml of the sql-server with ordinal |this|. Ordinal 0 starts
+// as primary and the others as standby, all at epoch 1, until bootstrap or
+// another command assigns roles.
+func SqlServerConfig(cfg *Config, this int) string {
+	name := cfg.StatefulSetName
+	var b strings.Builder
+	b.WriteString("cluster:\n  standby_remotes:\n")
+	for i := 0; i < cfg.Replicas; i++ {
+		if i != this {
+			fmt.Fprintf(&b, "  - name: %s-%d\n    remote_url_template: http://%s-%d.%s-internal:%d/{database}\n", name, i, name, i, name, remotesAPIPort)
+		}
+	}
+	role := StandbyRoleValue
+	if this == 0 {
+		role = PrimaryRoleValue
+	}
+	fmt.Fprintf(&b, "  bootstrap_epoch: 1\n  bootstrap_role: %s\n  remotesapi:\n    port: %d\n", role, remotesAPIPort)
+	fmt.Fprintf(&b, "listener:\n  host: 0.0.0.0\n  port: %d\n", sqlServerPort)
+	if cfg.ServerTLSSecret != "" {
+		b.WriteString("  tls_key: /etc/dolt-tls/tls.key\n  tls_cert: /etc/dolt-tls/tls.crt\n")
+		if cfg.ServerRequireTLS {
+			b.WriteString("  require_secure_transport: true\n")
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,162 @@
+// Copyright 2023 DoltHub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/yaml"
+)
+
+func manifestsConfig() *Config {
+	return &Config{
+		Namespace:       "dolt",
+		StatefulSetName: "doltdb",
+		Replicas:        3,
+		StorageSize:     "10Gi",
+	}
+}
+
+// The generated StatefulSet, and the pods the StatefulSet controller would
+// create for it, must follow the conventions kubernetesCluster relies on.
+func TestManifestsConventions(t *testing.T) {
+	cfg := manifestsConfig()
+	objs, err := ClusterManifests(cfg)
+	assert.NoError(t, err)
+
+	var ss *appsv1.StatefulSet
+	var configs *corev1.ConfigMap
+	services := make(map[string]*corev1.Service)
+	for _, obj := range objs {
+		switch o := obj.(type) {
+		case *appsv1.StatefulSet:
+			ss = o
+		case *corev1.ConfigMap:
+			configs = o
+		case *corev1.Service:
+			services[o.Name] = o
+		}
+	}
+	if !assert.NotNil(t, ss) || !assert.NotNil(t, configs) {
+		return
+	}
+
+	cluster := &kubernetesCluster{Namespace: cfg.Namespace, ObjectName: cfg.StatefulSetName, StatefulSet: ss}
+	for i := 0; i < cfg.Replicas; i++ {
+		cluster.Pods = append(cluster.Pods, &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      fmt.Sprintf("%s-%d", ss.Name, i),
+				Namespace: ss.Namespace,
+				Labels:    ss.Spec.Template.Labels,
+			},
+			Spec: ss.Spec.Template.Spec,
+		})
+	}
+	assert.Equal(t, cfg.Replicas, cluster.NumReplicas())
+	assert.Equal(t, ManifestsDefaultImage, cluster.Image())
+	assert.Equal(t, appsv1.OnDeleteStatefulSetStrategyType, ss.Spec.UpdateStrategy.Type)
+
+	headless, ok := services[cluster.ServiceName()]
+	if assert.True(t, ok, "the headless service must be the StatefulSet's service") {
+		assert.Equal(t, corev1.ClusterIPNone, headless.Spec.ClusterIP)
+	}
+
+	for i := 0; i < cfg.Replicas; i++ {
+		instance := cluster.Instance(i)
+		assert.Equal(t, 3306, instance.Port())
+		assert.Equal(t, fmt.Sprintf("doltdb-%d.doltdb-internal.dolt", i), instance.Hostname())
+
+		config, ok := configs.Data[fmt.Sprintf("doltdb-%d.yaml", i)]
+		assert.True(t, ok)
+		assert.Contains(t, config, fmt.Sprintf("port: %d\n", instance.Port()))
+		for j := 0; j < cfg.Replicas; j++ {
+			remote := fmt.Sprintf("http://doltdb-%d.doltdb-internal:50051/{database}", j)
+			assert.Equal(t, i != j, strings.Contains(config, remote))
+		}
+	}
+
+	for svc, role := range map[string]string{"doltdb-rw": PrimaryRoleValue, "doltdb-ro": StandbyRoleValue} {
+		if !assert.Contains(t, services, svc) {
+			continue
+		}
+		selector := labels.SelectorFromSet(services[svc].Spec.Selector)
+		pod := labels.Set{RoleLabel: role}
+		for k, v := range ss.Spec.Template.Labels {
+			pod[k] = v
+		}
+		assert.True(t, selector.Matches(pod), "%s should select %s pods", svc, role)
+		delete(pod, RoleLabel)
+		assert.False(t, selector.Matches(pod), "%s should not select unlabeled pods", svc)
+	}
+}
+
+func TestManifestsTLS(t *testing.T) {
+	cfg := manifestsConfig()
+	cfg.ServerTLSSecret = "doltdb-tls"
+	cfg.ServerRequireTLS = true
+	assert.Equal(t, `cluster:
+  standby_remotes:
+  - name: doltdb-0
+    remote_url_template: http://doltdb-0.doltdb-internal:50051/{database}
+  - name: doltdb-2
+    remote_url_template: http://doltdb-2.doltdb-internal:50051/{database}
+  bootstrap_epoch: 1
+  bootstrap_role: standby
+  remotesapi:
+    port: 50051
+listener:
+  host: 0.0.0.0
+  port: 3306
+  tls_key: /etc/dolt-tls/tls.key
+  tls_cert: /etc/dolt-tls/tls.crt
+  require_secure_transport: true
+`, SqlServerConfig(cfg, 1))
+}
+
+func TestWriteManifests(t *testing.T) {
+	objs, err := ClusterManifests(manifestsConfig())
+	assert.NoError(t, err)
+	var buf bytes.Buffer
+	assert.NoError(t, WriteManifests(&buf, objs))
+
+	docs := strings.Split(buf.String(), "\n---\n")
+	assert.Len(t, docs, len(objs))
+	for _, doc := range docs {
+		var meta metav1.TypeMeta
+		assert.NoError(t, yaml.Unmarshal([]byte(doc), &meta))
+		assert.NotEmpty(t, meta.APIVersion)
+		assert.NotEmpty(t, meta.Kind)
+	}
+}
+
+func TestClusterManifestsValidates(t *testing.T) {
+	cfg := manifestsConfig()
+	cfg.Replicas = 1
+	_, err := ClusterManifests(cfg)
+	assert.Error(t, err)
+
+	cfg = manifestsConfig()
+	cfg.StorageSize = "lots"
+	_, err = ClusterManifests(cfg)
+	assert.Error(t, err)
+}
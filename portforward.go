@@ -0,0 +1,185 @@
+// Copyright 2023 DoltHub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/portforward"
+	"k8s.io/client-go/transport/spdy"
+)
+
+const ConnectViaDirect = "direct"
+const ConnectViaPortForward = "port-forward"
+
+// Opens port-forwards to pods through the Kubernetes API server, so that
+// doltclusterctl can reach their sql-servers from outside the cluster.
+//
+// A tunnel is opened the first time a pod's address is asked for, and is
+// reused until the pod is recreated or the API server drops the
+// connection, at which point the next request opens a new one. Close tears
+// all of them down.
+type PortForwarder struct {
+	config    *rest.Config
+	clientset *kubernetes.Clientset
+
+	mu      sync.Mutex
+	tunnels map[string]*tunnel
+	closed  bool
+}
+
+type tunnel struct {
+	uid       types.UID
+	localPort int
+	stop      chan struct{}
+	done      chan struct{}
+}
+
+func NewPortForwarder(config *rest.Config, clientset *kubernetes.Clientset) *PortForwarder {
+	return &PortForwarder{
+		config:    config,
+		clientset: clientset,
+		tunnels:   make(map[string]*tunnel),
+	}
+}
+
+// Returns the local host and port at which |port| of |pod| can be reached,
+// opening a tunnel to it if there is not already one open.
+func (pf *PortForwarder) Address(ctx context.Context, pod *corev1.Pod, port int) (string, int, error) {
+	key := fmt.Sprintf("%s/%s:%d", pod.Namespace, pod.Name, port)
+
+	pf.mu.Lock()
+	defer pf.mu.Unlock()
+	if pf.closed {
+		return "", 0, fmt.Errorf("cannot port-forward to pod %s/%s: port-forwarding has been shut down", pod.Namespace, pod.Name)
+	}
+	if t, ok := pf.tunnels[key]; ok {
+		if t.isOpen() && t.uid == pod.UID {
+			return "127.0.0.1", t.localPort, nil
+		}
+		t.close()
+		delete(pf.tunnels, key)
+	}
+
+	t, err := pf.open(ctx, pod, port)
+	if err != nil {
+		return "", 0, fmt.Errorf("error port-forwarding to pod %s/%s: %w", pod.Namespace, pod.Name, err)
+	}
+	pf.tunnels[key] = t
+	return "127.0.0.1", t.localPort, nil
+}
+
+func (pf *PortForwarder) open(ctx context.Context, pod *corev1.Pod, port int) (*tunnel, error) {
+	transport, upgrader, err := spdy.RoundTripperFor(pf.config)
+	if err != nil {
+		return nil, err
+	}
+	url := pf.clientset.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Namespace(pod.Namespace).
+		Name(pod.Name).
+		SubResource("portforward").
+		URL()
+	dialer := spdy.NewDialer(upgrader, &http.Client{Transport: transport}, http.MethodPost, url)
+
+	t := &tunnel{
+		uid:  pod.UID,
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	ready := make(chan struct{})
+	errOut := &portForwardErrors{name: pod.Namespace + "/" + pod.Name}
+	fw, err := portforward.NewOnAddresses(dialer, []string{"127.0.0.1"}, []string{fmt.Sprintf("0:%d", port)}, t.stop, ready, io.Discard, errOut)
+	if err != nil {
+		return nil, err
+	}
+
+	var fwErr error
+	go func() {
+		defer close(t.done)
+		fwErr = fw.ForwardPorts()
+	}()
+	select {
+	case <-ready:
+	case <-t.done:
+		if fwErr == nil {
+			fwErr = fmt.Errorf("port-forward closed before it was ready")
+		}
+		return nil, fwErr
+	case <-ctx.Done():
+		t.close()
+		return nil, ctx.Err()
+	}
+
+	ports, err := fw.GetPorts()
+	if err != nil {
+		t.close()
+		return nil, err
+	}
+	t.localPort = int(ports[0].Local)
+	log.Printf("port-forwarding 127.0.0.1:%d to pod %s/%s port %d", t.localPort, pod.Namespace, pod.Name, port)
+	return t, nil
+}
+
+// Tears down every open tunnel and waits for them to close. Addresses
+// returned before Close should not be used after it.
+func (pf *PortForwarder) Close() {
+	pf.mu.Lock()
+	defer pf.mu.Unlock()
+	for key, t := range pf.tunnels {
+		t.close()
+		delete(pf.tunnels, key)
+	}
+	pf.closed = true
+}
+
+func (t *tunnel) isOpen() bool {
+	select {
+	case <-t.done:
+		return false
+	default:
+		return true
+	}
+}
+
+func (t *tunnel) close() {
+	select {
+	case <-t.stop:
+	default:
+		close(t.stop)
+	}
+	<-t.done
+}
+
+// Logs the errors a port-forward reports for individual connections, such
+// as a sql-server which is not listening yet.
+type portForwardErrors struct {
+	name string
+}
+
+func (w *portForwardErrors) Write(p []byte) (int, error) {
+	log.Printf("WARNING: port-forward to pod %s: %s", w.name, strings.TrimSpace(string(p)))
+	return len(p), nil
+}
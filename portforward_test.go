@@ -0,0 +1,70 @@
+// Copyright 2023 DoltHub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type tunneledInstance struct {
+	mockInstance
+}
+
+func (i *tunneledInstance) ConnectAddress(context.Context) (string, int, error) {
+	return "127.0.0.1", 43306, nil
+}
+
+func TestConnectAddress(t *testing.T) {
+	t.Run("Instance", func(t *testing.T) {
+		host, port, err := ConnectAddress(context.Background(), &mockInstance{hostname: "dolt-0.dolt-internal.dolt"})
+		assert.NoError(t, err)
+		assert.Equal(t, "dolt-0.dolt-internal.dolt", host)
+		assert.Equal(t, 3306, port)
+	})
+	t.Run("ConnectableInstance", func(t *testing.T) {
+		instance := &tunneledInstance{mockInstance{hostname: "dolt-0.dolt-internal.dolt"}}
+		host, port, err := ConnectAddress(context.Background(), instance)
+		assert.NoError(t, err)
+		assert.Equal(t, "127.0.0.1", host)
+		assert.Equal(t, 43306, port)
+		// Replication remotes still refer to the instance by its hostname.
+		assert.Equal(t, "dolt-0.dolt-internal.dolt", instance.Hostname())
+	})
+	t.Run("KubernetesWithoutTunnels", func(t *testing.T) {
+		cluster := &kubernetesCluster{
+			Namespace:   "dolt",
+			ObjectName:  "dolt",
+			StatefulSet: &appsv1.StatefulSet{Spec: appsv1.StatefulSetSpec{ServiceName: "dolt-internal"}},
+			Pods:        []*corev1.Pod{{ObjectMeta: metav1.ObjectMeta{Name: "dolt-0", Namespace: "dolt"}}},
+		}
+		host, port, err := ConnectAddress(context.Background(), cluster.Instance(0))
+		assert.NoError(t, err)
+		assert.Equal(t, "dolt-0.dolt-internal.dolt", host)
+		assert.Equal(t, 3306, port)
+	})
+}
+
+func TestPortForwarderClosed(t *testing.T) {
+	pf := NewPortForwarder(nil, nil)
+	pf.Close()
+	_, _, err := pf.Address(context.Background(), &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "dolt-0", Namespace: "dolt"}}, 3306)
+	assert.ErrorContains(t, err, "shut down")
+}
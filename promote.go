@@ -0,0 +1,125 @@
+// Copyright 2023 DoltHub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Promote makes an operator-chosen instance the primary at a fresh epoch.
+// Every other reachable instance, including a current primary or instances
+// in detected_broken_config, is made a standby at the same epoch.
+type Promote struct {
+	// The ordinal or the name of the instance to promote.
+	Target string
+}
+
+func (cmd Promote) Run(ctx context.Context, cfg *Config, cluster Cluster) error {
+	dbstates := LoadDBStates(ctx, cfg, cluster)
+
+	target, err := ResolveInstance(dbstates, cmd.Target)
+	if err != nil {
+		return fmt.Errorf("cannot promote %s: %w", cmd.Target, err)
+	}
+	newPrimary := dbstates[target].Instance
+
+	if dbstates[target].Err != nil {
+		return fmt.Errorf("cannot promote %s: %w", newPrimary.Name(), dbstates[target].Err)
+	}
+	if dbstates[target].Role == "primary" {
+		return fmt.Errorf("cannot promote %s: it is already in role primary. Run applyprimarylabels if its labels are wrong.", newPrimary.Name())
+	}
+
+	problems := PromoteTargetProblems(dbstates, target)
+	if len(problems) > 0 && !cfg.Force {
+		return fmt.Errorf("refusing to promote %s: %s. Run with -force to promote it anyway.", newPrimary.Name(), problems[0])
+	}
+	for _, p := range problems {
+		log.Printf("WARNING: %s; promoting anyway because of -force", p)
+	}
+
+	nextepoch := HighestEpoch(dbstates) + 1
+
+	log.Printf("promoting %s to primary at epoch %d", newPrimary.Name(), nextepoch)
+
+	for _, state := range dbstates {
+		err := state.Instance.MarkRoleStandby(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	log.Printf("labeled all pods as standby")
+
+	err = AssumeRoles(ctx, cfg, dbstates, target, nextepoch)
+	if err != nil {
+		return err
+	}
+
+	err = newPrimary.MarkRolePrimary(ctx)
+	if err != nil {
+		return err
+	}
+	log.Printf("applied primary label to %s", newPrimary.Name())
+
+	return nil
+}
+
+// Returns the reasons it is unsafe to promote the instance at |target|.
+//
+// If there is a current primary, |target| must be fully caught up according
+// to the primary's dolt_cluster_status. Otherwise, no other reachable
+// standby, or server in detected_broken_config, may have received writes more
+// recently than |target|.
+func PromoteTargetProblems(dbstates []DBState, target int) []string {
+	name := dbstates[target].Instance.Name()
+
+	var problems []string
+	if currentprimary, _, err := CurrentPrimaryAndEpoch(dbstates); err == nil {
+		primaryName := dbstates[currentprimary].Instance.Name()
+		rows := StandbyStatusRows(dbstates, currentprimary, target)
+		if len(rows) == 0 {
+			problems = append(problems, fmt.Sprintf("the current primary, %s, does not report replication status for %s", primaryName, name))
+		}
+		for _, row := range rows {
+			if row.CurrentError.Valid {
+				problems = append(problems, fmt.Sprintf("replication of database %s to %s has error: %s", row.Database, name, row.CurrentError.String))
+			} else if !row.ReplicationLag.Valid {
+				problems = append(problems, fmt.Sprintf("replication lag of database %s to %s is unknown", row.Database, name))
+			} else if row.ReplicationLag.Int64 > 0 {
+				problems = append(problems, fmt.Sprintf("database %s on %s is %dms behind the current primary, %s", row.Database, name, row.ReplicationLag.Int64, primaryName))
+			}
+		}
+		return problems
+	}
+
+	updated := OldestLastUpdate(dbstates[target])
+	for i, state := range dbstates {
+		if i == target || state.Err != nil {
+			continue
+		}
+		if state.Role != "standby" && state.Role != "detected_broken_config" {
+			continue
+		}
+		other := OldestLastUpdate(state)
+		if other != (time.Time{}) && other.After(updated) {
+			problems = append(problems, fmt.Sprintf("%s has received writes more recently than %s", state.Instance.Name(), name))
+		}
+	}
+	return problems
+}
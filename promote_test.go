@@ -0,0 +1,112 @@
+// Copyright 2023 DoltHub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPromoteTargetProblems(t *testing.T) {
+	primaryState := func(lag sql.NullInt64, currentError sql.NullString) DBState {
+		return DBState{
+			Role:     "primary",
+			Epoch:    10,
+			Instance: &mockInstance{name: "dolt-0", hostname: "dolt-0.dolt-internal.default"},
+			Status: []StatusRow{{
+				Database:       "mydb",
+				Role:           "primary",
+				Epoch:          10,
+				Remote:         "dolt-1",
+				ReplicationLag: lag,
+				CurrentError:   currentError,
+			}},
+			Remotes: []DBRemote{{
+				Database: "mydb",
+				Name:     "dolt-1",
+				URL:      "http://dolt-1.dolt-internal:50051/mydb",
+			}},
+		}
+	}
+	standbyState := DBState{
+		Role:     "standby",
+		Epoch:    10,
+		Instance: &mockInstance{name: "dolt-1", hostname: "dolt-1.dolt-internal.default"},
+	}
+	t.Run("WithPrimary", func(t *testing.T) {
+		t.Run("CaughtUp", func(t *testing.T) {
+			res := PromoteTargetProblems([]DBState{
+				primaryState(sql.NullInt64{Valid: true}, sql.NullString{}),
+				standbyState,
+			}, 1)
+			assert.Len(t, res, 0)
+		})
+		t.Run("Lagging", func(t *testing.T) {
+			res := PromoteTargetProblems([]DBState{
+				primaryState(sql.NullInt64{Valid: true, Int64: 1500}, sql.NullString{}),
+				standbyState,
+			}, 1)
+			assert.Equal(t, []string{"database mydb on dolt-1 is 1500ms behind the current primary, dolt-0"}, res)
+		})
+		t.Run("UnknownLag", func(t *testing.T) {
+			res := PromoteTargetProblems([]DBState{
+				primaryState(sql.NullInt64{}, sql.NullString{}),
+				standbyState,
+			}, 1)
+			assert.Equal(t, []string{"replication lag of database mydb to dolt-1 is unknown"}, res)
+		})
+		t.Run("CurrentError", func(t *testing.T) {
+			res := PromoteTargetProblems([]DBState{
+				primaryState(sql.NullInt64{Valid: true}, sql.NullString{Valid: true, String: "connection refused"}),
+				standbyState,
+			}, 1)
+			assert.Equal(t, []string{"replication of database mydb to dolt-1 has error: connection refused"}, res)
+		})
+		t.Run("NoStatus", func(t *testing.T) {
+			res := PromoteTargetProblems([]DBState{{
+				Role:     "primary",
+				Epoch:    10,
+				Instance: &mockInstance{name: "dolt-0", hostname: "dolt-0.dolt-internal.default"},
+			}, standbyState}, 1)
+			assert.Equal(t, []string{"the current primary, dolt-0, does not report replication status for dolt-1"}, res)
+		})
+	})
+	t.Run("WithoutPrimary", func(t *testing.T) {
+		earlier := time.Now().Add(-1 * time.Minute)
+		later := earlier.Add(30 * time.Second)
+		states := []DBState{{
+			Role:     "detected_broken_config",
+			Epoch:    10,
+			Instance: &mockInstance{name: "dolt-0"},
+			Status: []StatusRow{{
+				Database:   "mydb",
+				LastUpdate: sql.NullTime{Valid: true, Time: earlier},
+			}},
+		}, {
+			Role:     "detected_broken_config",
+			Epoch:    10,
+			Instance: &mockInstance{name: "dolt-1"},
+			Status: []StatusRow{{
+				Database:   "mydb",
+				LastUpdate: sql.NullTime{Valid: true, Time: later},
+			}},
+		}}
+		assert.Len(t, PromoteTargetProblems(states, 1), 0)
+		assert.Equal(t, []string{"dolt-1 has received writes more recently than dolt-0"}, PromoteTargetProblems(states, 0))
+	})
+}
@@ -0,0 +1,76 @@
+// Copyright 2023 DoltHub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Reconcile runs until it is stopped, applying the primary labels every
+// -interval and whenever the cluster changes. Each pass is bounded by
+// -timeout.
+type Reconcile struct{}
+
+func (Reconcile) LongRunning() {}
+
+func (cmd Reconcile) Run(ctx context.Context, cfg *Config, cluster Cluster) error {
+	var changes <-chan struct{}
+	if wc, ok := cluster.(WatchableCluster); ok {
+		var err error
+		changes, err = wc.Watch(ctx)
+		if err != nil {
+			return err
+		}
+	} else {
+		log.Printf("WARNING: cluster %s cannot be watched; only reconciling every %v", cluster.Name(), cfg.Interval)
+	}
+
+	ticker := time.NewTicker(cfg.Interval)
+	defer ticker.Stop()
+
+	log.Printf("reconciling labels of %s every %v", cluster.Name(), cfg.Interval)
+	for {
+		ReconcileOnce(ctx, cfg, cluster)
+		select {
+		case <-ctx.Done():
+			log.Printf("stopped reconciling labels of %s", cluster.Name())
+			return nil
+		case <-ticker.C:
+		case <-changes:
+		}
+	}
+}
+
+// Reloads |cluster|, if it can be reloaded, and applies the primary labels.
+// Errors are logged, since a later pass may succeed.
+func ReconcileOnce(ctx context.Context, cfg *Config, cluster Cluster) {
+	ctx, cancel := context.WithTimeout(ctx, cfg.Timeout)
+	defer cancel()
+
+	if wc, ok := cluster.(WatchableCluster); ok {
+		err := wc.Refresh(ctx)
+		if err != nil {
+			log.Printf("WARNING: could not reload cluster %s: %v", cluster.Name(), err)
+			return
+		}
+	}
+
+	err := ApplyPrimaryLabels{}.Run(ctx, cfg, cluster)
+	if err != nil && ctx.Err() == nil {
+		log.Printf("WARNING: could not reconcile labels: %v", err)
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright 2023 DoltHub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type mockWatchableCluster struct {
+	mockCluster
+	changes   chan struct{}
+	refreshes chan struct{}
+}
+
+func (c mockWatchableCluster) Refresh(context.Context) error {
+	c.refreshes <- struct{}{}
+	return nil
+}
+
+func (c mockWatchableCluster) Watch(context.Context) (<-chan struct{}, error) {
+	return c.changes, nil
+}
+
+func TestReconcile(t *testing.T) {
+	cluster := mockWatchableCluster{
+		changes:   make(chan struct{}),
+		refreshes: make(chan struct{}),
+	}
+	cfg := &Config{Interval: time.Hour, Timeout: time.Second}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- Reconcile{}.Run(ctx, cfg, cluster)
+	}()
+
+	// The first pass runs immediately, and every change causes another.
+	<-cluster.refreshes
+	cluster.changes <- struct{}{}
+	<-cluster.refreshes
+
+	cancel()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(10 * time.Second):
+		t.Fatal("Reconcile did not stop after its context was canceled")
+	}
+}
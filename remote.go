@@ -0,0 +1,284 @@
+// Copyright 2023 DoltHub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Where the Secret given with -remote-tls-secret is mounted in the remote
+// pod.
+const RemoteTLSDir = "/etc/doltclusterctl/tls"
+
+const remoteContainerName = "doltclusterctl"
+
+// The flags which configure the local doltclusterctl in -remote mode, and
+// which the remote one does not get. The remote one runs in the cluster, so
+// it uses its service account and connects to the pods directly.
+var remoteLocalFlags = []string{
+	"remote",
+	"remote-image",
+	"remote-service-account",
+	"remote-tls-secret",
+	"remote-credentials-secret",
+	"kubeconfig",
+	"context",
+	"connect-via",
+}
+
+// Removes the flags named |names| and their values from |args|, which are
+// parsed by |set|. Stops at the first argument which is not a flag, as
+// flag.FlagSet.Parse does, and returns everything from there on unchanged.
+func stripFlags(set *flag.FlagSet, args []string, names ...string) []string {
+	strip := make(map[string]bool)
+	for _, n := range names {
+		strip[n] = true
+	}
+	var res []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if len(arg) < 2 || arg[0] != '-' {
+			return append(res, args[i:]...)
+		}
+		if arg == "--" {
+			return append(res, args[i:]...)
+		}
+		name := strings.TrimLeft(arg, "-")
+		hasValue := false
+		if eq := strings.IndexByte(name, '='); eq != -1 {
+			name = name[:eq]
+			hasValue = true
+		}
+		// Non-boolean flags given as "-name value" take the next
+		// argument along with them.
+		takesNext := false
+		if f := set.Lookup(name); f != nil && !hasValue {
+			if bf, ok := f.Value.(interface{ IsBoolFlag() bool }); !ok || !bf.IsBoolFlag() {
+				takesNext = true
+			}
+		}
+		if strip[name] {
+			if takesNext {
+				i++
+			}
+			continue
+		}
+		res = append(res, arg)
+		if takesNext && i+1 < len(args) {
+			i++
+			res = append(res, args[i])
+		}
+	}
+	return res
+}
+
+// The arguments the remote doltclusterctl runs with, given the arguments
+// |args| the local one was run with.
+func RemoteArgs(cfg *Config, set *flag.FlagSet, args []string) []string {
+	if cfg.RemoteTLSSecret != "" {
+		// The remote doltclusterctl verifies the servers with the CA
+		// in the mounted Secret, instead of a local file.
+		args = stripFlags(set, args, append(remoteLocalFlags, "tls-ca")...)
+		return append([]string{"-tls-ca", RemoteTLSDir + "/ca.crt"}, args...)
+	}
+	return stripFlags(set, args, remoteLocalFlags...)
+}
+
+// The pod which runs doltclusterctl with |args| in the namespace of the
+// StatefulSet, as the service account given with -remote-service-account.
+func RemotePod(cfg *Config, args []string) *corev1.Pod {
+	container := corev1.Container{
+		Name:    remoteContainerName,
+		Image:   cfg.RemoteImage,
+		Command: []string{"doltclusterctl"},
+		Args:    args,
+	}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: "doltclusterctl-" + cfg.CommandStr + "-",
+			Namespace:    cfg.Namespace,
+			Labels: map[string]string{
+				"app": "doltclusterctl",
+			},
+		},
+		Spec: corev1.PodSpec{
+			ServiceAccountName: cfg.RemoteServiceAccount,
+			RestartPolicy:      corev1.RestartPolicyNever,
+		},
+	}
+	if cfg.RemoteTLSSecret != "" {
+		pod.Spec.Volumes = append(pod.Spec.Volumes, corev1.Volume{
+			Name: "tls",
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{SecretName: cfg.RemoteTLSSecret},
+			},
+		})
+		container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+			Name:      "tls",
+			MountPath: RemoteTLSDir,
+			ReadOnly:  true,
+		})
+	}
+	if cfg.RemoteCredentialsSecret != "" {
+		// The username is optional, as it is for a local
+		// doltclusterctl, which defaults to root.
+		optional := true
+		secretEnv := func(name, key string, optional *bool) corev1.EnvVar {
+			return corev1.EnvVar{
+				Name: name,
+				ValueFrom: &corev1.EnvVarSource{
+					SecretKeyRef: &corev1.SecretKeySelector{
+						LocalObjectReference: corev1.LocalObjectReference{Name: cfg.RemoteCredentialsSecret},
+						Key:                  key,
+						Optional:             optional,
+					},
+				},
+			}
+		}
+		container.Env = append(container.Env,
+			secretEnv("DOLT_USERNAME", "username", &optional),
+			secretEnv("DOLT_PASSWORD", "password", nil))
+	}
+	pod.Spec.Containers = []corev1.Container{container}
+	return pod
+}
+
+// Runs doltclusterctl with |args| in a pod in the cluster, streams its logs
+// to |out|, and deletes the pod once it exits. Returns an *ExitError with
+// the exit code of the remote doltclusterctl if it is not 0.
+func RunRemote(ctx context.Context, cfg *Config, clientset *kubernetes.Clientset, args []string, out io.Writer) error {
+	pods := clientset.CoreV1().Pods(cfg.Namespace)
+	pod, err := pods.Create(ctx, RemotePod(cfg, args), metav1.CreateOptions{})
+	if err != nil {
+		return fmt.Errorf("error creating pod to run doltclusterctl in namespace %s: %w", cfg.Namespace, err)
+	}
+	name := cfg.Namespace + "/" + pod.Name
+	log.Printf("running doltclusterctl in pod %s", name)
+	defer func() {
+		// The pod has to be deleted even if we were interrupted.
+		deleteCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		err := pods.Delete(deleteCtx, pod.Name, metav1.DeleteOptions{})
+		if err != nil {
+			log.Printf("WARNING: error deleting pod %s: %v", name, err)
+		}
+	}()
+
+	err = waitRemoteStarted(ctx, cfg, clientset, pod.Name)
+	if err != nil {
+		return err
+	}
+
+	opts := &corev1.PodLogOptions{Container: remoteContainerName, Follow: true}
+	for {
+		stream, err := pods.GetLogs(pod.Name, opts).Stream(ctx)
+		if err != nil {
+			return fmt.Errorf("error streaming logs of pod %s: %w", name, err)
+		}
+		_, copyErr := io.Copy(out, stream)
+		stream.Close()
+		if ctx.Err() != nil {
+			return fmt.Errorf("stopped waiting for pod %s: %w", name, ctx.Err())
+		}
+
+		// The log stream ends when the container exits, but it can
+		// also be dropped by the API server, in which case we pick up
+		// where it left off.
+		code, exited, err := remoteExitCode(ctx, clientset, cfg.Namespace, pod.Name)
+		if err != nil {
+			return err
+		}
+		if exited {
+			if code != 0 {
+				return &ExitError{Code: code, Err: fmt.Errorf("doltclusterctl in pod %s exited with code %d", name, code)}
+			}
+			return nil
+		}
+		log.Printf("WARNING: lost the logs of pod %s while it was running: %v", name, copyErr)
+		opts.SinceTime = &metav1.Time{Time: time.Now()}
+	}
+}
+
+// Waits for the remote pod to start running, for up to -wait-for-ready.
+// Fails early if the pod cannot start, for example because its image cannot
+// be pulled or a Secret it mounts does not exist.
+func waitRemoteStarted(ctx context.Context, cfg *Config, clientset *kubernetes.Clientset, podname string) error {
+	ctx, cancel := context.WithTimeout(ctx, cfg.WaitForReady)
+	defer cancel()
+	pods := clientset.CoreV1().Pods(cfg.Namespace)
+	for {
+		p, err := pods.Get(ctx, podname, metav1.GetOptions{})
+		if err == nil {
+			if p.Status.Phase != corev1.PodPending {
+				return nil
+			}
+			for _, c := range p.Status.ContainerStatuses {
+				if w := c.State.Waiting; w != nil && remoteStartFailures[w.Reason] {
+					return fmt.Errorf("pod %s/%s cannot start: %s: %s", cfg.Namespace, podname, w.Reason, w.Message)
+				}
+			}
+		}
+		select {
+		case <-time.After(250 * time.Millisecond):
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return fmt.Errorf("pod %s/%s did not start within -wait-for-ready %v", cfg.Namespace, podname, cfg.WaitForReady)
+			}
+			return ctx.Err()
+		}
+	}
+}
+
+var remoteStartFailures = map[string]bool{
+	"ErrImagePull":               true,
+	"ImagePullBackOff":           true,
+	"InvalidImageName":           true,
+	"CreateContainerConfigError": true,
+}
+
+// Returns the exit code of the doltclusterctl container of the remote pod,
+// and whether it has exited. Waits briefly for the status to catch up with
+// the end of the logs.
+func remoteExitCode(ctx context.Context, clientset *kubernetes.Clientset, namespace, podname string) (int, bool, error) {
+	pods := clientset.CoreV1().Pods(namespace)
+	for i := 0; i < 50; i++ {
+		p, err := pods.Get(ctx, podname, metav1.GetOptions{})
+		if err != nil {
+			return 0, false, fmt.Errorf("error loading pod %s/%s: %w", namespace, podname, err)
+		}
+		for _, c := range p.Status.ContainerStatuses {
+			if c.Name == remoteContainerName && c.State.Terminated != nil {
+				return int(c.State.Terminated.ExitCode), true, nil
+			}
+		}
+		select {
+		case <-time.After(100 * time.Millisecond):
+		case <-ctx.Done():
+			return 0, false, ctx.Err()
+		}
+	}
+	return 0, false, nil
+}
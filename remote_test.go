@@ -0,0 +1,118 @@
+// Copyright 2023 DoltHub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"flag"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestStripFlags(t *testing.T) {
+	var cfg Config
+	var set flag.FlagSet
+	cfg.InitFlagSet(&set)
+
+	t.Run("Nothing", func(t *testing.T) {
+		args := []string{"-n", "dolt", "status", "dolt"}
+		assert.Equal(t, args, stripFlags(&set, args, "kubeconfig"))
+	})
+	t.Run("ValueFlags", func(t *testing.T) {
+		args := []string{"-kubeconfig", "/home/dolt/.kube/config", "-n", "dolt", "--context=prod", "-to", "2", "gracefulfailover", "dolt"}
+		assert.Equal(t, []string{"-n", "dolt", "-to", "2", "gracefulfailover", "dolt"}, stripFlags(&set, args, "kubeconfig", "context"))
+	})
+	t.Run("BoolFlags", func(t *testing.T) {
+		args := []string{"-remote", "-yes", "-remote=true", "-force", "promote", "dolt", "1"}
+		assert.Equal(t, []string{"-yes", "-force", "promote", "dolt", "1"}, stripFlags(&set, args, "remote"))
+	})
+	t.Run("StopsAtSubcommand", func(t *testing.T) {
+		args := []string{"-remote", "status", "-remote", "dolt"}
+		assert.Equal(t, []string{"status", "-remote", "dolt"}, stripFlags(&set, args, "remote"))
+	})
+	t.Run("StopsAtDoubleDash", func(t *testing.T) {
+		args := []string{"-remote", "--", "-remote", "status", "dolt"}
+		assert.Equal(t, []string{"--", "-remote", "status", "dolt"}, stripFlags(&set, args, "remote"))
+	})
+}
+
+func TestRemoteArgs(t *testing.T) {
+	args := []string{"-remote", "-remote-image", "doltclusterctl:latest", "-context", "prod", "-connect-via", "port-forward", "-tls-ca", "testdata/validroots.pem", "-n", "dolt", "status", "dolt"}
+	t.Run("Default", func(t *testing.T) {
+		var cfg Config
+		var set flag.FlagSet
+		assert.NoError(t, cfg.Parse(&set, []string{"-remote", "-remote-image", "doltclusterctl:latest", "-context", "prod", "-n", "dolt", "status", "dolt"}))
+		assert.Equal(t, []string{"-n", "dolt", "status", "dolt"}, RemoteArgs(&cfg, &set, []string{"-remote", "-remote-image", "doltclusterctl:latest", "-context", "prod", "-n", "dolt", "status", "dolt"}))
+	})
+	t.Run("TLSSecret", func(t *testing.T) {
+		var cfg Config
+		var set flag.FlagSet
+		assert.NoError(t, cfg.Parse(&set, append([]string{"-remote-tls-secret", "dolt-tls"}, args...)))
+		assert.Equal(t, []string{"-tls-ca", "/etc/doltclusterctl/tls/ca.crt", "-n", "dolt", "status", "dolt"}, RemoteArgs(&cfg, &set, append([]string{"-remote-tls-secret", "dolt-tls"}, args...)))
+	})
+	t.Run("TLSCAWithoutSecret", func(t *testing.T) {
+		var cfg Config
+		var set flag.FlagSet
+		assert.Error(t, cfg.Parse(&set, args))
+	})
+}
+
+func TestRemotePod(t *testing.T) {
+	t.Run("Default", func(t *testing.T) {
+		cfg := &Config{Namespace: "dolt", CommandStr: "status", RemoteImage: "doltclusterctl:latest", RemoteServiceAccount: "doltclusterctl"}
+		pod := RemotePod(cfg, []string{"-n", "dolt", "status", "dolt"})
+		assert.Equal(t, "dolt", pod.Namespace)
+		assert.Equal(t, "doltclusterctl-status-", pod.GenerateName)
+		assert.Equal(t, "doltclusterctl", pod.Spec.ServiceAccountName)
+		assert.Equal(t, corev1.RestartPolicyNever, pod.Spec.RestartPolicy)
+		if assert.Len(t, pod.Spec.Containers, 1) {
+			c := pod.Spec.Containers[0]
+			assert.Equal(t, "doltclusterctl:latest", c.Image)
+			assert.Equal(t, []string{"doltclusterctl"}, c.Command)
+			assert.Equal(t, []string{"-n", "dolt", "status", "dolt"}, c.Args)
+			assert.Empty(t, c.Env)
+			assert.Empty(t, c.VolumeMounts)
+		}
+		assert.Empty(t, pod.Spec.Volumes)
+	})
+	t.Run("Secrets", func(t *testing.T) {
+		cfg := &Config{
+			Namespace:               "dolt",
+			CommandStr:              "status",
+			RemoteImage:             "doltclusterctl:latest",
+			RemoteServiceAccount:    "doltclusterctl",
+			RemoteTLSSecret:         "dolt-tls",
+			RemoteCredentialsSecret: "dolt-credentials",
+		}
+		pod := RemotePod(cfg, []string{"status", "dolt"})
+		if assert.Len(t, pod.Spec.Volumes, 1) {
+			assert.Equal(t, "dolt-tls", pod.Spec.Volumes[0].Secret.SecretName)
+		}
+		c := pod.Spec.Containers[0]
+		if assert.Len(t, c.VolumeMounts, 1) {
+			assert.Equal(t, RemoteTLSDir, c.VolumeMounts[0].MountPath)
+		}
+		if assert.Len(t, c.Env, 2) {
+			assert.Equal(t, "DOLT_USERNAME", c.Env[0].Name)
+			assert.Equal(t, "username", c.Env[0].ValueFrom.SecretKeyRef.Key)
+			assert.True(t, *c.Env[0].ValueFrom.SecretKeyRef.Optional)
+			assert.Equal(t, "DOLT_PASSWORD", c.Env[1].Name)
+			assert.Equal(t, "dolt-credentials", c.Env[1].ValueFrom.SecretKeyRef.Name)
+			assert.Equal(t, "password", c.Env[1].ValueFrom.SecretKeyRef.Key)
+			assert.Nil(t, c.Env[1].ValueFrom.SecretKeyRef.Optional)
+		}
+	})
+}
@@ -0,0 +1,112 @@
+// Copyright 2023 DoltHub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+)
+
+// Reseed throws away the storage of a standby, so that it is recreated
+// empty and the primary replicates every database to it from scratch. It
+// waits for the recreated standby to be ready and then for the primary to
+// report it caught up for every database, and only then labels it standby.
+type Reseed struct {
+	// The ordinal or the name of the instance to reseed.
+	Target string
+}
+
+func (Reseed) Confirms() {}
+
+func (cmd Reseed) Run(ctx context.Context, cfg *Config, cluster Cluster) error {
+	dbstates := LoadDBStates(ctx, cfg, cluster)
+
+	target, err := ResolveInstance(dbstates, cmd.Target)
+	if err != nil {
+		return fmt.Errorf("cannot reseed %s: %w", cmd.Target, err)
+	}
+	instance, ok := dbstates[target].Instance.(ReseedableInstance)
+	if !ok {
+		return fmt.Errorf("cannot reseed %s: its storage cannot be recreated", dbstates[target].Instance.Name())
+	}
+
+	primary, _, err := CurrentPrimaryAndEpoch(dbstates)
+	if err != nil {
+		return fmt.Errorf("cannot reseed %s: there must be a primary to replicate to it: %w", instance.Name(), err)
+	}
+	if primary == target {
+		return fmt.Errorf("cannot reseed %s: it is the current primary. Run gracefulfailover first.", instance.Name())
+	}
+	if dbstates[target].Err != nil && instance.Role() == RolePrimary {
+		return fmt.Errorf("cannot reseed %s: it is labeled primary but is unreachable", instance.Name())
+	}
+
+	if !cfg.Yes {
+		ok, err := Confirm(fmt.Sprintf("Delete all of the data on %s and replicate it again from %s?", instance.Name(), dbstates[primary].Instance.Name()))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errors.New("not reseeding: not confirmed")
+		}
+	}
+
+	err = instance.MarkRoleUnknown(ctx)
+	if err != nil {
+		return err
+	}
+	log.Printf("removed role label from %s", instance.Name())
+
+	reseedCtx, cancel := context.WithTimeout(ctx, cfg.WaitForReady)
+	defer cancel()
+	err = instance.Reseed(reseedCtx)
+	if err != nil {
+		return err
+	}
+	err = WaitForDBReady(reseedCtx, cfg, instance)
+	if err != nil {
+		return err
+	}
+
+	return markStandbyWhenCaughtUp(ctx, cfg, cluster, target, WaitForStandbyCaughtUp)
+}
+
+// Waits with |waitCaughtUp| for the primary to report the standby at
+// ordinal |standby| of |cluster| caught up, and only then labels it
+// standby, so that a standby which was created without data receives no
+// standby traffic until it has it. It stays unlabeled if it does not
+// catch up.
+func markStandbyWhenCaughtUp(ctx context.Context, cfg *Config, cluster Cluster, standby int, waitCaughtUp func(context.Context, *Config, Cluster, int, time.Duration) ([]StatusRow, error)) error {
+	instance := cluster.Instance(standby)
+	log.Printf("pod is ready %s; waiting for it to be caught up", instance.Name())
+
+	rows, err := waitCaughtUp(ctx, cfg, cluster, standby, cfg.MaxLag)
+	if err != nil {
+		RenderCaughtUp(os.Stdout, rows, cfg.MaxLag)
+		return err
+	}
+	log.Printf("%s is caught up across %d databases", instance.Name(), len(rows))
+
+	err = instance.MarkRoleStandby(ctx)
+	if err != nil {
+		return err
+	}
+	log.Printf("applied standby label to %s", instance.Name())
+	return nil
+}
@@ -0,0 +1,52 @@
+// Copyright 2023 DoltHub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMarkStandbyWhenCaughtUp(t *testing.T) {
+	newCluster := func() *instancesCluster {
+		return &instancesCluster{name: "dolt/dolt", instances: []Instance{
+			&mockInstance{name: "dolt/dolt-0", role: RolePrimary},
+			&mockInstance{name: "dolt/dolt-1", role: RoleUnknown},
+		}}
+	}
+	t.Run("CaughtUp", func(t *testing.T) {
+		cluster := newCluster()
+		var roleWhileWaiting Role
+		err := markStandbyWhenCaughtUp(context.Background(), &Config{}, cluster, 1, func(_ context.Context, _ *Config, _ Cluster, standby int, _ time.Duration) ([]StatusRow, error) {
+			roleWhileWaiting = cluster.Instance(standby).Role()
+			return nil, nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, RoleUnknown, roleWhileWaiting)
+		assert.Equal(t, RoleStandby, cluster.Instance(1).Role())
+	})
+	t.Run("NotCaughtUp", func(t *testing.T) {
+		cluster := newCluster()
+		err := markStandbyWhenCaughtUp(context.Background(), &Config{}, cluster, 1, func(context.Context, *Config, Cluster, int, time.Duration) ([]StatusRow, error) {
+			return nil, errors.New("standbys of dolt/dolt did not catch up")
+		})
+		assert.Error(t, err)
+		assert.Equal(t, RoleUnknown, cluster.Instance(1).Role())
+	})
+}
@@ -0,0 +1,275 @@
+// Copyright 2023 DoltHub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// ResolveBrokenConfig recovers a cluster in which servers are in
+// detected_broken_config, typically after a split brain. It collects the
+// epoch, replication status and branch heads of every server, proposes the
+// server which should become primary, and after confirmation makes it the
+// primary and every other server a standby at a new epoch.
+type ResolveBrokenConfig struct{}
+
+func (ResolveBrokenConfig) Confirms() {}
+
+func (cmd ResolveBrokenConfig) Run(ctx context.Context, cfg *Config, cluster Cluster) error {
+	dbstates := LoadDBStates(ctx, cfg, cluster)
+
+	broken := false
+	for _, state := range dbstates {
+		if state.Err != nil {
+			if !cfg.Force {
+				return fmt.Errorf("cannot resolve broken config: %w. Run with -force to proceed without it.", state.Err)
+			}
+			log.Printf("WARNING: %v; proceeding without it because of -force", state.Err)
+		} else if state.Role == "detected_broken_config" {
+			broken = true
+		}
+	}
+	if !broken && !cfg.Force {
+		return errors.New("cannot resolve broken config: no pod is in detected_broken_config. Use promote or gracefulfailover to change the primary.")
+	}
+
+	heads := make([][]DatabaseHeads, len(dbstates))
+	for i, state := range dbstates {
+		if state.Err != nil {
+			continue
+		}
+		var err error
+		heads[i], err = LoadHeads(ctx, cfg, state.Instance)
+		if err != nil {
+			return fmt.Errorf("cannot resolve broken config: %w", err)
+		}
+	}
+
+	RenderBrokenConfig(os.Stdout, dbstates, heads)
+
+	winner, reason := ProposeWinner(dbstates, heads)
+	if winner == -1 {
+		return errors.New("cannot resolve broken config: no pod was reachable")
+	}
+	newPrimary := dbstates[winner].Instance
+	nextepoch := HighestSeenEpoch(dbstates) + 1
+
+	log.Printf("proposing %s as the new primary: %s", newPrimary.Name(), reason)
+
+	if !cfg.Yes {
+		ok, err := Confirm(fmt.Sprintf("Make %s primary, and every other pod standby, at epoch %d?", newPrimary.Name(), nextepoch))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errors.New("not resolving broken config: not confirmed")
+		}
+	}
+
+	log.Printf("making %s primary at epoch %d", newPrimary.Name(), nextepoch)
+
+	for _, state := range dbstates {
+		err := state.Instance.MarkRoleStandby(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	log.Printf("labeled all pods as standby")
+
+	err := AssumeRoles(ctx, cfg, dbstates, winner, nextepoch)
+	if err != nil {
+		return err
+	}
+
+	err = newPrimary.MarkRolePrimary(ctx)
+	if err != nil {
+		return err
+	}
+	log.Printf("applied primary label to %s", newPrimary.Name())
+
+	return nil
+}
+
+// Returns the highest epoch any server in |dbstates| reports, either as its
+// own epoch or as the epoch of one of its databases.
+func HighestSeenEpoch(dbstates []DBState) int {
+	highestepoch := HighestEpoch(dbstates)
+	for _, state := range dbstates {
+		for _, status := range state.Status {
+			if status.Epoch > highestepoch {
+				highestepoch = status.Epoch
+			}
+		}
+	}
+	return highestepoch
+}
+
+// Returns the newest last_update across the dolt_cluster_status rows of
+// |state|, or the zero time if no row has a last_update.
+func NewestLastUpdate(state DBState) time.Time {
+	var newest time.Time
+	for _, status := range state.Status {
+		if status.LastUpdate.Valid && status.LastUpdate.Time.After(newest) {
+			newest = status.LastUpdate.Time
+		}
+	}
+	return newest
+}
+
+// Chooses the reachable server which most likely has the most recent data.
+// Prefers the highest epoch, then the most recent replication activity, then
+// the most branches across all databases, then the lowest ordinal. Returns
+// -1 if no server is reachable, along with a description of why the server
+// was chosen.
+func ProposeWinner(dbstates []DBState, heads [][]DatabaseHeads) (int, string) {
+	numBranches := func(i int) int {
+		n := 0
+		for _, h := range heads[i] {
+			n += len(h.Branches)
+		}
+		return n
+	}
+
+	winner := -1
+	for i, state := range dbstates {
+		if state.Err != nil {
+			continue
+		}
+		if winner == -1 {
+			winner = i
+			continue
+		}
+		best := dbstates[winner]
+		if state.Epoch != best.Epoch {
+			if state.Epoch > best.Epoch {
+				winner = i
+			}
+			continue
+		}
+		if updated, bestUpdated := NewestLastUpdate(state), NewestLastUpdate(best); !updated.Equal(bestUpdated) {
+			if updated.After(bestUpdated) {
+				winner = i
+			}
+			continue
+		}
+		if numBranches(i) > numBranches(winner) {
+			winner = i
+		}
+	}
+	if winner == -1 {
+		return -1, ""
+	}
+
+	updated := "never"
+	if t := NewestLastUpdate(dbstates[winner]); t != (time.Time{}) {
+		updated = t.Format(time.RFC3339)
+	}
+	return winner, fmt.Sprintf("it has epoch %d, last replicated writes at %s, and has %d branches across %d databases", dbstates[winner].Epoch, updated, numBranches(winner), len(heads[winner]))
+}
+
+// Writes the role, epoch and replication activity of each server, and the
+// head of every branch on each server, to |w|.
+func RenderBrokenConfig(w io.Writer, dbstates []DBState, heads [][]DatabaseHeads) {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+
+	fmt.Fprintln(tw, "POD\tROLE\tEPOCH\tLAST_UPDATE\tERROR")
+	for _, state := range dbstates {
+		if state.Err != nil {
+			fmt.Fprintf(tw, "%s\t-\t-\t-\t%v\n", state.Instance.Name(), state.Err)
+			continue
+		}
+		updated := "-"
+		if t := NewestLastUpdate(state); t != (time.Time{}) {
+			updated = t.Format(time.RFC3339)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%s\t-\n", state.Instance.Name(), state.Role, state.Epoch, updated)
+	}
+	tw.Flush()
+
+	type key struct {
+		db     string
+		branch string
+	}
+	hashes := make(map[key][]string)
+	for i := range dbstates {
+		for _, h := range heads[i] {
+			for branch := range h.Branches {
+				k := key{h.Database, branch}
+				if _, ok := hashes[k]; !ok {
+					hashes[k] = make([]string, len(dbstates))
+				}
+			}
+		}
+	}
+	for i := range dbstates {
+		for _, h := range heads[i] {
+			for branch, hash := range h.Branches {
+				hashes[key{h.Database, branch}][i] = hash
+			}
+		}
+	}
+	var keys []key
+	for k := range hashes {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].db != keys[j].db {
+			return keys[i].db < keys[j].db
+		}
+		return keys[i].branch < keys[j].branch
+	})
+
+	fmt.Fprintln(w)
+	header := []string{"DATABASE", "BRANCH"}
+	for _, state := range dbstates {
+		header = append(header, state.Instance.Name())
+	}
+	header = append(header, "DIVERGED")
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for _, k := range keys {
+		row := []string{k.db, k.branch}
+		diverged := "no"
+		first, seen := "", false
+		for i, hash := range hashes[k] {
+			if dbstates[i].Err != nil {
+				row = append(row, "?")
+				continue
+			}
+			if hash == "" {
+				row = append(row, "-")
+			} else {
+				row = append(row, hash)
+			}
+			if !seen {
+				first, seen = hash, true
+			} else if hash != first {
+				diverged = "yes"
+			}
+		}
+		row = append(row, diverged)
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	tw.Flush()
+}
@@ -0,0 +1,113 @@
+// Copyright 2023 DoltHub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProposeWinner(t *testing.T) {
+	earlier := time.Now().Add(-1 * time.Minute)
+	later := earlier.Add(30 * time.Second)
+	withUpdate := func(state DBState, updated time.Time) DBState {
+		state.Status = []StatusRow{{
+			Database:   "mydb",
+			LastUpdate: sql.NullTime{Valid: true, Time: updated},
+		}}
+		return state
+	}
+	t.Run("NoneReachable", func(t *testing.T) {
+		res, _ := ProposeWinner([]DBState{{
+			Instance: &mockInstance{name: "dolt-0"},
+			Err:      errors.New("connection refused"),
+		}}, make([][]DatabaseHeads, 1))
+		assert.Equal(t, -1, res)
+	})
+	t.Run("HighestEpoch", func(t *testing.T) {
+		res, _ := ProposeWinner([]DBState{
+			withUpdate(DBState{Role: "detected_broken_config", Epoch: 10, Instance: &mockInstance{name: "dolt-0"}}, later),
+			withUpdate(DBState{Role: "detected_broken_config", Epoch: 11, Instance: &mockInstance{name: "dolt-1"}}, earlier),
+		}, make([][]DatabaseHeads, 2))
+		assert.Equal(t, 1, res)
+	})
+	t.Run("MostRecentUpdate", func(t *testing.T) {
+		res, _ := ProposeWinner([]DBState{
+			withUpdate(DBState{Role: "detected_broken_config", Epoch: 10, Instance: &mockInstance{name: "dolt-0"}}, earlier),
+			withUpdate(DBState{Role: "detected_broken_config", Epoch: 10, Instance: &mockInstance{name: "dolt-1"}}, later),
+		}, make([][]DatabaseHeads, 2))
+		assert.Equal(t, 1, res)
+	})
+	t.Run("MostBranches", func(t *testing.T) {
+		res, _ := ProposeWinner([]DBState{
+			{Role: "detected_broken_config", Epoch: 10, Instance: &mockInstance{name: "dolt-0"}},
+			{Role: "detected_broken_config", Epoch: 10, Instance: &mockInstance{name: "dolt-1"}},
+		}, [][]DatabaseHeads{{{
+			Database: "mydb",
+			Branches: map[string]string{"main": "abc"},
+		}}, {{
+			Database: "mydb",
+			Branches: map[string]string{"main": "abc", "feature": "def"},
+		}}})
+		assert.Equal(t, 1, res)
+	})
+	t.Run("SkipsUnreachable", func(t *testing.T) {
+		res, _ := ProposeWinner([]DBState{
+			{Instance: &mockInstance{name: "dolt-0"}, Err: errors.New("connection refused")},
+			{Role: "detected_broken_config", Epoch: 10, Instance: &mockInstance{name: "dolt-1"}},
+		}, make([][]DatabaseHeads, 2))
+		assert.Equal(t, 1, res)
+	})
+}
+
+func TestHighestSeenEpoch(t *testing.T) {
+	res := HighestSeenEpoch([]DBState{{
+		Epoch: 10,
+		Status: []StatusRow{{
+			Database: "mydb",
+			Epoch:    12,
+		}},
+	}, {
+		Epoch: 11,
+	}})
+	assert.Equal(t, 12, res)
+}
+
+func TestRenderBrokenConfig(t *testing.T) {
+	var buf bytes.Buffer
+	RenderBrokenConfig(&buf, []DBState{
+		{Role: "detected_broken_config", Epoch: 10, Instance: &mockInstance{name: "dolt-0"}},
+		{Role: "detected_broken_config", Epoch: 10, Instance: &mockInstance{name: "dolt-1"}},
+	}, [][]DatabaseHeads{{{
+		Database: "mydb",
+		Branches: map[string]string{"main": "abc"},
+	}}, {{
+		Database: "mydb",
+		Branches: map[string]string{"main": "def", "feature": "ghi"},
+	}}})
+	assert.Equal(t, `POD     ROLE                    EPOCH  LAST_UPDATE  ERROR
+dolt-0  detected_broken_config  10     -            -
+dolt-1  detected_broken_config  10     -            -
+
+DATABASE  BRANCH   dolt-0  dolt-1  DIVERGED
+mydb      feature  -       ghi     yes
+mydb      main     abc     def     yes
+`, buf.String())
+}
@@ -0,0 +1,56 @@
+// Copyright 2023 DoltHub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+)
+
+// Restart restarts a single pod. If it is the current primary, it first
+// performs a graceful failover, honoring -min-caughtup-standbys and -to, so
+// that the pod is a standby when it is restarted.
+type Restart struct {
+	// The ordinal or the name of the instance to restart.
+	Target string
+}
+
+func (cmd Restart) Run(ctx context.Context, cfg *Config, cluster Cluster) error {
+	dbstates := LoadDBStates(ctx, cfg, cluster)
+
+	target, err := ResolveInstance(dbstates, cmd.Target)
+	if err != nil {
+		return fmt.Errorf("cannot restart %s: %w", cmd.Target, err)
+	}
+	instance := dbstates[target].Instance
+
+	if dbstates[target].Err != nil {
+		if instance.Role() == RolePrimary {
+			return fmt.Errorf("cannot restart %s: it is labeled primary but is unreachable, so it cannot be failed over gracefully. Run promotestandby first.", instance.Name())
+		}
+		log.Printf("WARNING: restarting unreachable pod %s: %v", instance.Name(), dbstates[target].Err)
+	}
+
+	if dbstates[target].Role == "primary" {
+		log.Printf("%s is the primary; failing over before restarting it", instance.Name())
+		err = GracefulFailover{}.Run(ctx, cfg, cluster)
+		if err != nil {
+			return fmt.Errorf("cannot restart %s: %w", instance.Name(), err)
+		}
+	}
+
+	return restartStandby(ctx, cfg, instance)
+}
@@ -0,0 +1,231 @@
+// Copyright 2023 DoltHub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"sort"
+)
+
+// Scale changes the number of replicas in the cluster. Before scaling down,
+// it fails the primary over to a replica which remains. After scaling up, it
+// waits for the new replicas to be caught up before labeling them standby.
+// It refuses to scale unless the standby remotes of every replica which
+// remains already match the new membership.
+type Scale struct {
+	// The number of replicas to scale to.
+	Replicas int
+}
+
+func (cmd Scale) Run(ctx context.Context, cfg *Config, cluster Cluster) error {
+	sc, ok := cluster.(ScalableCluster)
+	if !ok {
+		return fmt.Errorf("cannot scale %s: it cannot be scaled", cluster.Name())
+	}
+	current := cluster.NumReplicas()
+
+	dbstates := LoadDBStates(ctx, cfg, cluster)
+	for _, state := range dbstates {
+		if state.Err != nil {
+			if !cfg.Force {
+				return fmt.Errorf("cannot scale %s: %w. Run with -force to proceed without it.", cluster.Name(), state.Err)
+			}
+			log.Printf("WARNING: %v; proceeding without it because of -force", state.Err)
+		}
+	}
+
+	members := make([]string, cmd.Replicas)
+	for i := range members {
+		members[i] = sc.MemberHostname(i)
+	}
+	problems := ValidateRemoteMembership(dbstates, members)
+	if len(problems) > 0 && !cfg.Force {
+		return fmt.Errorf("refusing to scale %s to %d: %s. Update the cluster config of the pods and restart them with rollingrestart first, or run with -force.", cluster.Name(), cmd.Replicas, problems[0])
+	}
+	for _, p := range problems {
+		log.Printf("WARNING: %s; scaling anyway because of -force", p)
+	}
+
+	if cmd.Replicas == current {
+		log.Printf("%s already has %d replicas", cluster.Name(), current)
+		return nil
+	} else if cmd.Replicas < current {
+		return scaleDown(ctx, cfg, sc, dbstates, cmd.Replicas)
+	}
+	return scaleUp(ctx, cfg, sc, cmd.Replicas)
+}
+
+func scaleDown(ctx context.Context, cfg *Config, cluster ScalableCluster, dbstates []DBState, n int) error {
+	var removed []int
+	for i := n; i < len(dbstates); i++ {
+		removed = append(removed, i)
+	}
+
+	currentprimary, _, err := CurrentPrimaryAndEpoch(dbstates)
+	if err != nil {
+		return fmt.Errorf("cannot scale %s down: %w", cluster.Name(), err)
+	}
+	if currentprimary >= n {
+		target := -1
+		if cfg.FailoverTo != "" {
+			target, err = ResolveInstance(dbstates, cfg.FailoverTo)
+			if err != nil {
+				return fmt.Errorf("cannot scale %s down: %w", cluster.Name(), err)
+			}
+			if target >= n {
+				return fmt.Errorf("cannot scale %s down: %s, given with -to, would be removed", cluster.Name(), dbstates[target].Instance.Name())
+			}
+		} else {
+			target = DrainFailoverTarget(dbstates, removed)
+			if target == -1 {
+				return fmt.Errorf("cannot scale %s down: the primary, %s, would be removed and no reachable standby remains", cluster.Name(), dbstates[currentprimary].Instance.Name())
+			}
+		}
+
+		log.Printf("the primary, %s, would be removed; failing over to %s", dbstates[currentprimary].Instance.Name(), dbstates[target].Instance.Name())
+		failoverCfg := *cfg
+		failoverCfg.FailoverTo = dbstates[target].Instance.Name()
+		err = GracefulFailover{}.Run(ctx, &failoverCfg, cluster)
+		if err != nil {
+			return fmt.Errorf("cannot scale %s down: %w", cluster.Name(), err)
+		}
+	}
+
+	for _, i := range removed {
+		instance := dbstates[i].Instance
+		err := instance.MarkRoleUnknown(ctx)
+		if err != nil {
+			return err
+		}
+		log.Printf("removed role label from %s", instance.Name())
+	}
+
+	scaleCtx, cancel := context.WithTimeout(ctx, cfg.WaitForReady)
+	defer cancel()
+	err = cluster.Scale(scaleCtx, n)
+	if err != nil {
+		return err
+	}
+	log.Printf("scaled %s down to %d replicas", cluster.Name(), n)
+	return nil
+}
+
+func scaleUp(ctx context.Context, cfg *Config, cluster ScalableCluster, n int) error {
+	current := cluster.NumReplicas()
+
+	scaleCtx, cancel := context.WithTimeout(ctx, cfg.WaitForReady)
+	defer cancel()
+	err := cluster.Scale(scaleCtx, n)
+	if err != nil {
+		return err
+	}
+	for i := current; i < n; i++ {
+		err := WaitForDBReady(scaleCtx, cfg, cluster.Instance(i))
+		if err != nil {
+			return err
+		}
+	}
+
+	dbstates := LoadDBStates(ctx, cfg, cluster)
+	members := make([]string, n)
+	for i := range members {
+		members[i] = cluster.MemberHostname(i)
+	}
+	problems := ValidateRemoteMembership(dbstates[current:], members)
+	if len(problems) > 0 {
+		return fmt.Errorf("scaled %s up to %d replicas, but %s. Update the cluster config of the new pods and restart them.", cluster.Name(), n, problems[0])
+	}
+
+	for i := current; i < n; i++ {
+		err := markStandbyWhenCaughtUp(ctx, cfg, cluster, i, WaitForStandbyCaughtUp)
+		if err != nil {
+			return err
+		}
+	}
+
+	log.Printf("scaled %s up to %d replicas", cluster.Name(), n)
+	return nil
+}
+
+// Checks that the standby remotes of each reachable server in |dbstates|
+// which is a member of the cluster reach exactly the other members, whose
+// hostnames are given by ordinal in |members|. Servers without databases
+// have no standby remotes to check. Returns a description of each
+// mismatch.
+func ValidateRemoteMembership(dbstates []DBState, members []string) []string {
+	var problems []string
+	for _, state := range dbstates {
+		if state.Err != nil || len(state.Remotes) == 0 {
+			continue
+		}
+		self := -1
+		for j, hostname := range members {
+			if hostname == state.Instance.Hostname() {
+				self = j
+			}
+		}
+		if self == -1 {
+			continue
+		}
+
+		// The members each database of this server replicates to.
+		reached := make(map[string]map[int]bool)
+		extra := make(map[string]bool)
+		for _, remote := range state.Remotes {
+			if reached[remote.Database] == nil {
+				reached[remote.Database] = make(map[int]bool)
+			}
+			parsed, err := url.Parse(remote.URL)
+			if err != nil {
+				extra[remote.URL] = true
+				continue
+			}
+			member := -1
+			for j, hostname := range members {
+				if RemoteHostMatches(hostname, parsed.Hostname()) {
+					member = j
+				}
+			}
+			if member == -1 || member == self {
+				extra[parsed.Hostname()] = true
+			} else {
+				reached[remote.Database][member] = true
+			}
+		}
+
+		missing := make(map[int]bool)
+		for _, r := range reached {
+			for j := range members {
+				if j != self && !r[j] {
+					missing[j] = true
+				}
+			}
+		}
+
+		var mine []string
+		for host := range extra {
+			mine = append(mine, fmt.Sprintf("pod %s replicates to %s, which is not another member of the cluster", state.Instance.Name(), host))
+		}
+		for j := range missing {
+			mine = append(mine, fmt.Sprintf("pod %s does not replicate every database to %s", state.Instance.Name(), members[j]))
+		}
+		sort.Strings(mine)
+		problems = append(problems, mine...)
+	}
+	return problems
+}
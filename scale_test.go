@@ -0,0 +1,70 @@
+// Copyright 2023 DoltHub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateRemoteMembership(t *testing.T) {
+	members := []string{
+		"dolt-0.dolt-internal.dolt",
+		"dolt-1.dolt-internal.dolt",
+		"dolt-2.dolt-internal.dolt",
+	}
+	state := func(ordinal int, remotes ...string) DBState {
+		res := DBState{Instance: &mockInstance{name: fmt.Sprintf("dolt/dolt-%d", ordinal), hostname: members[ordinal]}}
+		for _, r := range remotes {
+			res.Remotes = append(res.Remotes, DBRemote{Database: "mydb", Name: r, URL: "http://" + r + ".dolt-internal:50051/mydb"})
+		}
+		return res
+	}
+	t.Run("Matches", func(t *testing.T) {
+		res := ValidateRemoteMembership([]DBState{
+			state(0, "dolt-1", "dolt-2"),
+			state(1, "dolt-0", "dolt-2"),
+			state(2, "dolt-0", "dolt-1"),
+		}, members)
+		assert.Empty(t, res)
+	})
+	t.Run("RemovedMember", func(t *testing.T) {
+		res := ValidateRemoteMembership([]DBState{
+			state(0, "dolt-1", "dolt-2"),
+			state(1, "dolt-0"),
+			state(2, "dolt-0", "dolt-1"),
+		}, members[:2])
+		assert.Equal(t, []string{"pod dolt/dolt-0 replicates to dolt-2.dolt-internal, which is not another member of the cluster"}, res)
+	})
+	t.Run("AddedMember", func(t *testing.T) {
+		res := ValidateRemoteMembership([]DBState{
+			state(0, "dolt-1"),
+			state(1, "dolt-0", "dolt-2"),
+		}, members)
+		assert.Equal(t, []string{"pod dolt/dolt-0 does not replicate every database to dolt-2.dolt-internal.dolt"}, res)
+	})
+	t.Run("SkipsUnreachableAndEmpty", func(t *testing.T) {
+		unreachable := state(0, "dolt-2")
+		unreachable.Err = errors.New("connection refused")
+		res := ValidateRemoteMembership([]DBState{
+			unreachable,
+			state(1),
+		}, members[:2])
+		assert.Empty(t, res)
+	})
+}
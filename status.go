@@ -0,0 +1,130 @@
+// Copyright 2023 DoltHub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Status prints the state of every sql-server in the cluster, along with the
+// traffic role label currently applied to its instance. It returns an error
+// if the cluster is not healthy, so that it can be used in scripts.
+type Status struct{}
+
+func (cmd Status) Run(ctx context.Context, cfg *Config, cluster Cluster) error {
+	dbstates := LoadDBStates(ctx, cfg, cluster)
+	problems := StatusProblems(dbstates)
+
+	RenderStatus(os.Stdout, dbstates, problems)
+
+	if len(problems) > 0 {
+		return fmt.Errorf("cluster %s is unhealthy: %s", cluster.Name(), problems[0])
+	}
+	log.Printf("cluster %s is healthy", cluster.Name())
+	return nil
+}
+
+// Returns a description of everything which is wrong with the cluster as
+// described by |dbstates|. Returns an empty slice if the cluster is healthy.
+func StatusProblems(dbstates []DBState) []string {
+	var problems []string
+	var primaries []string
+	for _, state := range dbstates {
+		name := state.Instance.Name()
+		if state.Err != nil {
+			problems = append(problems, fmt.Sprintf("pod %s is unreachable: %v", name, state.Err))
+			continue
+		}
+		switch state.Role {
+		case "primary":
+			primaries = append(primaries, name)
+			if label := state.Instance.Role(); label != RolePrimary {
+				problems = append(problems, fmt.Sprintf("pod %s is in role primary but is labeled %s", name, label))
+			}
+		case "standby":
+			if label := state.Instance.Role(); label != RoleStandby {
+				problems = append(problems, fmt.Sprintf("pod %s is in role standby but is labeled %s", name, label))
+			}
+		case "detected_broken_config":
+			problems = append(problems, fmt.Sprintf("pod %s is in detected_broken_config", name))
+		default:
+			problems = append(problems, fmt.Sprintf("pod %s is in unexpected role %s", name, state.Role))
+		}
+	}
+	if len(primaries) == 0 {
+		problems = append(problems, "no reachable pod is in role primary")
+	} else if len(primaries) > 1 {
+		problems = append(problems, fmt.Sprintf("more than one reachable pod is in role primary: %s", strings.Join(primaries, ", ")))
+	}
+	return problems
+}
+
+// Writes a human-readable rendering of |dbstates| and |problems| to |w|.
+func RenderStatus(w io.Writer, dbstates []DBState, problems []string) {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+
+	fmt.Fprintln(tw, "POD\tLABEL\tROLE\tEPOCH\tVERSION\tERROR")
+	for _, state := range dbstates {
+		if state.Err != nil {
+			fmt.Fprintf(tw, "%s\t%s\t-\t-\t-\t%v\n", state.Instance.Name(), state.Instance.Role(), state.Err)
+		} else {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\t-\n", state.Instance.Name(), state.Instance.Role(), state.Role, state.Epoch, state.Version)
+		}
+	}
+	tw.Flush()
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(tw, "POD\tDATABASE\tROLE\tEPOCH\tREMOTE\tURL\tLAG_MILLIS\tLAST_UPDATE\tCURRENT_ERROR")
+	for _, state := range dbstates {
+		for _, status := range state.Status {
+			url := "-"
+			for _, remote := range state.Remotes {
+				if remote.Database == status.Database && remote.Name == status.Remote {
+					url = remote.URL
+				}
+			}
+			lag := "-"
+			if status.ReplicationLag.Valid {
+				lag = strconv.FormatInt(status.ReplicationLag.Int64, 10)
+			}
+			lastUpdate := "-"
+			if status.LastUpdate.Valid {
+				lastUpdate = status.LastUpdate.Time.Format(time.RFC3339)
+			}
+			currentError := "-"
+			if status.CurrentError.Valid {
+				currentError = status.CurrentError.String
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\t%s\t%s\t%s\t%s\n", state.Instance.Name(), status.Database, status.Role, status.Epoch, status.Remote, url, lag, lastUpdate, currentError)
+		}
+	}
+	tw.Flush()
+
+	if len(problems) > 0 {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "PROBLEMS")
+		for _, p := range problems {
+			fmt.Fprintf(w, "  %s\n", p)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright 2023 DoltHub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatusProblems(t *testing.T) {
+	t.Run("Healthy", func(t *testing.T) {
+		res := StatusProblems([]DBState{{
+			Role:     "primary",
+			Epoch:    10,
+			Instance: &mockInstance{name: "dolt-0", role: RolePrimary},
+		}, {
+			Role:     "standby",
+			Epoch:    10,
+			Instance: &mockInstance{name: "dolt-1", role: RoleStandby},
+		}})
+		assert.Len(t, res, 0)
+	})
+	t.Run("LabelMismatch", func(t *testing.T) {
+		res := StatusProblems([]DBState{{
+			Role:     "primary",
+			Epoch:    10,
+			Instance: &mockInstance{name: "dolt-0", role: RoleStandby},
+		}, {
+			Role:     "standby",
+			Epoch:    10,
+			Instance: &mockInstance{name: "dolt-1", role: RoleUnknown},
+		}})
+		assert.Equal(t, []string{
+			"pod dolt-0 is in role primary but is labeled standby",
+			"pod dolt-1 is in role standby but is labeled unknown",
+		}, res)
+	})
+	t.Run("Unreachable", func(t *testing.T) {
+		res := StatusProblems([]DBState{{
+			Role:     "primary",
+			Epoch:    10,
+			Instance: &mockInstance{name: "dolt-0", role: RolePrimary},
+		}, {
+			Instance: &mockInstance{name: "dolt-1", role: RoleStandby},
+			Err:      errors.New("connection refused"),
+		}})
+		assert.Equal(t, []string{"pod dolt-1 is unreachable: connection refused"}, res)
+	})
+	t.Run("NoPrimary", func(t *testing.T) {
+		res := StatusProblems([]DBState{{
+			Role:     "standby",
+			Epoch:    10,
+			Instance: &mockInstance{name: "dolt-0", role: RoleStandby},
+		}})
+		assert.Equal(t, []string{"no reachable pod is in role primary"}, res)
+	})
+	t.Run("MultiplePrimaries", func(t *testing.T) {
+		res := StatusProblems([]DBState{{
+			Role:     "primary",
+			Epoch:    10,
+			Instance: &mockInstance{name: "dolt-0", role: RolePrimary},
+		}, {
+			Role:     "primary",
+			Epoch:    11,
+			Instance: &mockInstance{name: "dolt-1", role: RolePrimary},
+		}})
+		assert.Equal(t, []string{"more than one reachable pod is in role primary: dolt-0, dolt-1"}, res)
+	})
+	t.Run("DetectedBrokenConfig", func(t *testing.T) {
+		res := StatusProblems([]DBState{{
+			Role:     "detected_broken_config",
+			Epoch:    10,
+			Instance: &mockInstance{name: "dolt-0", role: RolePrimary},
+		}, {
+			Role:     "detected_broken_config",
+			Epoch:    10,
+			Instance: &mockInstance{name: "dolt-1", role: RoleStandby},
+		}})
+		assert.Equal(t, []string{
+			"pod dolt-0 is in detected_broken_config",
+			"pod dolt-1 is in detected_broken_config",
+			"no reachable pod is in role primary",
+		}, res)
+	})
+}
+
+func TestRenderStatus(t *testing.T) {
+	var buf bytes.Buffer
+	RenderStatus(&buf, []DBState{{
+		Role:     "primary",
+		Epoch:    10,
+		Version:  "1.30.0",
+		Instance: &mockInstance{name: "dolt-0", role: RolePrimary},
+		Status: []StatusRow{{
+			Database:       "mydb",
+			Role:           "primary",
+			Epoch:          10,
+			Remote:         "dolt-1",
+			ReplicationLag: sql.NullInt64{Valid: true, Int64: 0},
+			CurrentError:   sql.NullString{Valid: true, String: "failed"},
+		}},
+		Remotes: []DBRemote{{
+			Database: "mydb",
+			Name:     "dolt-1",
+			URL:      "http://dolt-1.dolt-internal:50051/mydb",
+		}},
+	}, {
+		Instance: &mockInstance{name: "dolt-1", role: RoleStandby},
+		Err:      errors.New("connection refused"),
+	}}, []string{"pod dolt-1 is unreachable: connection refused"})
+	assert.Equal(t, `POD     LABEL    ROLE     EPOCH  VERSION  ERROR
+dolt-0  primary  primary  10     1.30.0   -
+dolt-1  standby  -        -      -        connection refused
+
+POD     DATABASE  ROLE     EPOCH  REMOTE  URL                                     LAG_MILLIS  LAST_UPDATE  CURRENT_ERROR
+dolt-0  mydb      primary  10     dolt-1  http://dolt-1.dolt-internal:50051/mydb  0           -            failed
+
+PROBLEMS
+  pod dolt-1 is unreachable: connection refused
+`, buf.String())
+}
@@ -0,0 +1,173 @@
+// Copyright 2023 DoltHub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// Upgrade changes the image of the cluster to -image and restarts every pod
+// onto it: first the standbys, from the highest ordinal to the lowest, and
+// then, after failing over to a standby running the new version, the old
+// primary. It stops as soon as a restarted pod reports an unexpected
+// dolt_version(), or a version which lacks a capability the old version had.
+//
+// Pods which are already running -image are not restarted, so an upgrade
+// which stopped part way through can be resumed by running it again.
+type Upgrade struct{}
+
+func (cmd Upgrade) Run(ctx context.Context, cfg *Config, cluster Cluster) error {
+	uc, ok := cluster.(UpgradableCluster)
+	if !ok {
+		return fmt.Errorf("cannot upgrade %s: changing its image is not supported", cluster.Name())
+	}
+
+	dbstates := LoadDBStates(ctx, cfg, cluster)
+
+	for _, state := range dbstates {
+		if state.Err != nil {
+			return fmt.Errorf("cannot upgrade: %w", state.Err)
+		}
+		if state.Role == "detected_broken_config" {
+			return fmt.Errorf("cannot upgrade: found pod %s in detected_broken_config", state.Instance.Name())
+		}
+	}
+
+	curprimary, highestepoch, err := CurrentPrimaryAndEpoch(dbstates)
+	if err != nil {
+		return fmt.Errorf("cannot upgrade: %w", err)
+	}
+
+	oldPrimary := dbstates[curprimary].Instance
+	check := UpgradeVersionCheck{
+		OldVersion: dbstates[curprimary].Version,
+		Expected:   cfg.ExpectVersion,
+	}
+	stopped := func(err error) error {
+		return fmt.Errorf("stopping upgrade: %w. %s is still the primary; run upgrade again to resume.", err, oldPrimary.Name())
+	}
+
+	if uc.Image() != cfg.Image {
+		log.Printf("changing image of %s from %s to %s", cluster.Name(), uc.Image(), cfg.Image)
+		err = uc.SetImage(ctx, cfg.Image)
+		if err != nil {
+			return fmt.Errorf("cannot upgrade: %w", err)
+		}
+	}
+
+	// Restarts the pod with ordinal |i| onto the new image, unless it is
+	// already running it, and checks the version it reports.
+	upgradePod := func(i int) error {
+		instance := dbstates[i].Instance
+		if uc.InstanceImage(i) == cfg.Image {
+			log.Printf("pod %s is already running %s", instance.Name(), cfg.Image)
+		} else {
+			err := restartStandby(ctx, cfg, instance)
+			if err != nil {
+				return err
+			}
+		}
+		state := LoadDBState(ctx, cfg, instance)
+		if state.Err != nil {
+			return state.Err
+		}
+		err := check.Check(instance.Name(), state.Version)
+		if err != nil {
+			return err
+		}
+		log.Printf("pod %s is running dolt %s", instance.Name(), state.Version)
+		return nil
+	}
+
+	// In order from highest ordinal to lowest, we upgrade each standby...
+	for i := len(dbstates) - 1; i >= 0; i-- {
+		if i == curprimary {
+			continue
+		}
+		err := upgradePod(i)
+		if err != nil {
+			return stopped(err)
+		}
+	}
+
+	if uc.InstanceImage(curprimary) == cfg.Image {
+		err := upgradePod(curprimary)
+		if err != nil {
+			return stopped(err)
+		}
+		log.Printf("every pod of %s is running %s", cluster.Name(), cfg.Image)
+		return nil
+	}
+
+	// Every standby has been upgraded and checked. We failover the
+	// primary to the lowest-ordinal standby and then upgrade the old
+	// primary.
+	nextprimary := -1
+	for i := range dbstates {
+		if i != curprimary {
+			nextprimary = i
+			break
+		}
+	}
+	if nextprimary == -1 {
+		return stopped(fmt.Errorf("%s has no standby to fail over to", cluster.Name()))
+	}
+	newPrimary := dbstates[nextprimary].Instance
+
+	log.Printf("decided pod %s will be next primary", newPrimary.Name())
+
+	err = failoverTo(ctx, cfg, oldPrimary, newPrimary, highestepoch+1)
+	if err != nil {
+		return err
+	}
+
+	err = upgradePod(curprimary)
+	if err != nil {
+		return fmt.Errorf("stopping upgrade: %w. %s is the new primary.", err, newPrimary.Name())
+	}
+
+	log.Printf("every pod of %s is running %s", cluster.Name(), cfg.Image)
+	return nil
+}
+
+// UpgradeVersionCheck checks the dolt_version() reported by each pod after
+// it is upgraded.
+type UpgradeVersionCheck struct {
+	// The version the primary reported before the upgrade.
+	OldVersion string
+
+	// The version every upgraded pod must report. If empty, it becomes
+	// the version the first upgraded pod reports.
+	Expected string
+}
+
+// Returns an error if |version|, reported by the upgraded pod |name|, is not
+// the expected version, or if it does not support something OldVersion
+// did.
+func (c *UpgradeVersionCheck) Check(name, version string) error {
+	if c.Expected == "" {
+		c.Expected = version
+	}
+	if version != c.Expected {
+		return fmt.Errorf("pod %s came back running dolt %s, but expected %s", name, version, c.Expected)
+	}
+	if dropped := DroppedCapabilities(c.OldVersion, version); len(dropped) > 0 {
+		return fmt.Errorf("pod %s came back running dolt %s, which does not support %s, which dolt %s did", name, version, strings.Join(dropped, ", "), c.OldVersion)
+	}
+	return nil
+}
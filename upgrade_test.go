@@ -0,0 +1,38 @@
+// Copyright 2023 DoltHub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpgradeVersionCheck(t *testing.T) {
+	t.Run("FirstPodSetsExpected", func(t *testing.T) {
+		c := UpgradeVersionCheck{OldVersion: "1.6.0"}
+		assert.NoError(t, c.Check("dolt-2", "1.7.0"))
+		assert.NoError(t, c.Check("dolt-1", "1.7.0"))
+		assert.EqualError(t, c.Check("dolt-0", "1.6.0"), "pod dolt-0 came back running dolt 1.6.0, but expected 1.7.0")
+	})
+	t.Run("ExpectVersion", func(t *testing.T) {
+		c := UpgradeVersionCheck{OldVersion: "1.6.0", Expected: "1.8.0"}
+		assert.EqualError(t, c.Check("dolt-1", "1.7.0"), "pod dolt-1 came back running dolt 1.7.0, but expected 1.8.0")
+	})
+	t.Run("DroppedCapability", func(t *testing.T) {
+		c := UpgradeVersionCheck{OldVersion: "1.6.0"}
+		assert.EqualError(t, c.Check("dolt-1", "1.5.0"), "pod dolt-1 came back running dolt 1.5.0, which does not support dolt_cluster_transition_to_standby, which dolt 1.6.0 did")
+	})
+}
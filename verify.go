@@ -0,0 +1,225 @@
+// Copyright 2023 DoltHub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"maps"
+	"os"
+	"slices"
+	"text/tabwriter"
+	"time"
+)
+
+// Verify checks that every standby holds the same data as the primary, by
+// comparing the head and the working set of every branch of every database.
+// With -wait-for-convergence, it keeps checking for up to that long while
+// the only mismatches are ones which replication could still resolve.
+type Verify struct{}
+
+func (cmd Verify) Run(ctx context.Context, cfg *Config, cluster Cluster) error {
+	deadline := time.Now().Add(cfg.WaitForConvergence)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	inconsistent := func(mismatches []Mismatch) error {
+		RenderMismatches(os.Stdout, mismatches)
+		return fmt.Errorf("cluster %s is inconsistent: found %d mismatches between the primary and its standbys", cluster.Name(), len(mismatches))
+	}
+	// The mismatches of the last check, reported if -timeout expires
+	// while we wait for them to resolve.
+	var last []Mismatch
+	for {
+		mismatches, numDatabases, err := VerifyOnce(ctx, cfg, cluster)
+		if err != nil {
+			if ctx.Err() != nil && last != nil {
+				return inconsistent(last)
+			}
+			return fmt.Errorf("cannot verify: %w", err)
+		}
+		if len(mismatches) == 0 {
+			fmt.Printf("every standby matches the primary across %d databases\n", numDatabases)
+			return nil
+		}
+		if time.Now().After(deadline) || !CanConverge(mismatches) {
+			return inconsistent(mismatches)
+		}
+		last = mismatches
+		log.Printf("found %d mismatches; waiting for standbys to converge", len(mismatches))
+		select {
+		case <-ctx.Done():
+			return inconsistent(last)
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// Compares every standby of |cluster| against its primary. Returns the
+// mismatches and the number of databases on the primary.
+func VerifyOnce(ctx context.Context, cfg *Config, cluster Cluster) ([]Mismatch, int, error) {
+	dbstates := LoadDBStates(ctx, cfg, cluster)
+	for _, state := range dbstates {
+		if state.Err != nil {
+			return nil, 0, state.Err
+		}
+		if state.Role == "detected_broken_config" {
+			return nil, 0, fmt.Errorf("found pod %s in detected_broken_config", state.Instance.Name())
+		}
+	}
+	primary, _, err := CurrentPrimaryAndEpoch(dbstates)
+	if err != nil {
+		return nil, 0, err
+	}
+	primaryInstance := dbstates[primary].Instance
+
+	// The standbys are loaded before the primary, so that writes which
+	// happen while we load make standbys look behind, not diverged.
+	standbyHeads := make([][]DatabaseHeads, len(dbstates))
+	for i, state := range dbstates {
+		if i == primary {
+			continue
+		}
+		standbyHeads[i], err = loadHeadsAndWorkingSets(ctx, cfg, state.Instance)
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+	primaryHeads, err := loadHeadsAndWorkingSets(ctx, cfg, primaryInstance)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	isAncestor := func(dbname, ancestor, descendant string) (bool, error) {
+		return IsAncestor(ctx, cfg, primaryInstance, dbname, ancestor, descendant)
+	}
+	var mismatches []Mismatch
+	for i, state := range dbstates {
+		if i == primary {
+			continue
+		}
+		mismatches = append(mismatches, CompareHeads(state.Instance.Name(), primaryHeads, standbyHeads[i], isAncestor)...)
+	}
+	return mismatches, len(primaryHeads), nil
+}
+
+func loadHeadsAndWorkingSets(ctx context.Context, cfg *Config, instance Instance) ([]DatabaseHeads, error) {
+	heads, err := LoadHeads(ctx, cfg, instance)
+	if err != nil {
+		return nil, err
+	}
+	err = LoadWorkingSets(ctx, cfg, instance, heads)
+	if err != nil {
+		return nil, err
+	}
+	return heads, nil
+}
+
+const (
+	MismatchMissing    = "missing"
+	MismatchExtra      = "extra"
+	MismatchBehind     = "behind"
+	MismatchDiverged   = "diverged"
+	MismatchWorkingSet = "working set differs"
+)
+
+// A difference between the data on a standby and on the primary.
+type Mismatch struct {
+	Standby  string
+	Database string
+	// Empty if the whole database is missing or extra.
+	Branch string
+	Kind   string
+	Detail string
+}
+
+// Returns true if replication could still resolve every one of
+// |mismatches|. A standby whose branch has diverged from the primary's
+// will never converge.
+func CanConverge(mismatches []Mismatch) bool {
+	for _, m := range mismatches {
+		if m.Kind == MismatchDiverged {
+			return false
+		}
+	}
+	return true
+}
+
+// Compares the databases on the standby |standby| against those on the
+// primary. |isAncestor| reports whether one commit is an ancestor of
+// another according to the primary.
+func CompareHeads(standby string, primary, heads []DatabaseHeads, isAncestor func(dbname, ancestor, descendant string) (bool, error)) []Mismatch {
+	onStandby := make(map[string]DatabaseHeads)
+	for _, h := range heads {
+		onStandby[h.Database] = h
+	}
+	onPrimary := make(map[string]bool)
+
+	var res []Mismatch
+	for _, p := range primary {
+		onPrimary[p.Database] = true
+		s, ok := onStandby[p.Database]
+		if !ok {
+			res = append(res, Mismatch{standby, p.Database, "", MismatchMissing, "database is not on the standby"})
+			continue
+		}
+		for _, branch := range slices.Sorted(maps.Keys(p.Branches)) {
+			phead := p.Branches[branch]
+			shead, ok := s.Branches[branch]
+			if !ok {
+				res = append(res, Mismatch{standby, p.Database, branch, MismatchMissing, "branch is not on the standby"})
+			} else if shead != phead {
+				detail := fmt.Sprintf("standby is at %s, primary is at %s", shead, phead)
+				behind, err := isAncestor(p.Database, shead, phead)
+				if err != nil {
+					res = append(res, Mismatch{standby, p.Database, branch, MismatchDiverged, fmt.Sprintf("%s, which the primary does not have: %v", detail, err)})
+				} else if behind {
+					res = append(res, Mismatch{standby, p.Database, branch, MismatchBehind, detail})
+				} else {
+					res = append(res, Mismatch{standby, p.Database, branch, MismatchDiverged, detail})
+				}
+			} else if p.WorkingSets[branch] != s.WorkingSets[branch] {
+				res = append(res, Mismatch{standby, p.Database, branch, MismatchWorkingSet, fmt.Sprintf("standby working set is %s, primary working set is %s", s.WorkingSets[branch], p.WorkingSets[branch])})
+			}
+		}
+		for _, branch := range slices.Sorted(maps.Keys(s.Branches)) {
+			if _, ok := p.Branches[branch]; !ok {
+				res = append(res, Mismatch{standby, p.Database, branch, MismatchExtra, "branch is not on the primary"})
+			}
+		}
+	}
+	for _, h := range heads {
+		if !onPrimary[h.Database] {
+			res = append(res, Mismatch{standby, h.Database, "", MismatchExtra, "database is not on the primary"})
+		}
+	}
+	return res
+}
+
+// Writes a table of |mismatches| to |w|.
+func RenderMismatches(w io.Writer, mismatches []Mismatch) {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "POD\tDATABASE\tBRANCH\tPROBLEM\tDETAIL")
+	for _, m := range mismatches {
+		branch := m.Branch
+		if branch == "" {
+			branch = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", m.Standby, m.Database, branch, m.Kind, m.Detail)
+	}
+	tw.Flush()
+}
@@ -0,0 +1,101 @@
+// Copyright 2023 DoltHub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompareHeads(t *testing.T) {
+	primary := []DatabaseHeads{{
+		Database:    "mydb",
+		Branches:    map[string]string{"main": "c3", "feature": "f1"},
+		WorkingSets: map[string]string{"main": "w3", "feature": "wf"},
+	}}
+	// c1 <- c2 <- c3 on the primary.
+	isAncestor := func(dbname, ancestor, descendant string) (bool, error) {
+		switch ancestor {
+		case "c1", "c2":
+			return true, nil
+		case "x1":
+			return false, nil
+		}
+		return false, errors.New("could not find commit")
+	}
+
+	t.Run("Matches", func(t *testing.T) {
+		res := CompareHeads("dolt-1", primary, primary, isAncestor)
+		assert.Len(t, res, 0)
+	})
+	t.Run("MissingDatabase", func(t *testing.T) {
+		res := CompareHeads("dolt-1", primary, nil, isAncestor)
+		assert.Equal(t, []Mismatch{{"dolt-1", "mydb", "", MismatchMissing, "database is not on the standby"}}, res)
+	})
+	t.Run("ExtraDatabase", func(t *testing.T) {
+		res := CompareHeads("dolt-1", nil, primary, isAncestor)
+		assert.Equal(t, []Mismatch{{"dolt-1", "mydb", "", MismatchExtra, "database is not on the primary"}}, res)
+	})
+	t.Run("Branches", func(t *testing.T) {
+		res := CompareHeads("dolt-1", primary, []DatabaseHeads{{
+			Database:    "mydb",
+			Branches:    map[string]string{"main": "c2", "old": "o1"},
+			WorkingSets: map[string]string{"main": "w2", "old": "wo"},
+		}}, isAncestor)
+		assert.Equal(t, []Mismatch{
+			{"dolt-1", "mydb", "feature", MismatchMissing, "branch is not on the standby"},
+			{"dolt-1", "mydb", "main", MismatchBehind, "standby is at c2, primary is at c3"},
+			{"dolt-1", "mydb", "old", MismatchExtra, "branch is not on the primary"},
+		}, res)
+		assert.True(t, CanConverge(res))
+	})
+	t.Run("Diverged", func(t *testing.T) {
+		res := CompareHeads("dolt-1", primary, []DatabaseHeads{{
+			Database:    "mydb",
+			Branches:    map[string]string{"main": "x1", "feature": "x2"},
+			WorkingSets: map[string]string{"main": "w3", "feature": "wf"},
+		}}, isAncestor)
+		assert.Equal(t, []Mismatch{
+			{"dolt-1", "mydb", "feature", MismatchDiverged, "standby is at x2, primary is at f1, which the primary does not have: could not find commit"},
+			{"dolt-1", "mydb", "main", MismatchDiverged, "standby is at x1, primary is at c3"},
+		}, res)
+		assert.False(t, CanConverge(res))
+	})
+	t.Run("WorkingSet", func(t *testing.T) {
+		res := CompareHeads("dolt-1", primary, []DatabaseHeads{{
+			Database:    "mydb",
+			Branches:    map[string]string{"main": "c3", "feature": "f1"},
+			WorkingSets: map[string]string{"main": "w2", "feature": "wf"},
+		}}, isAncestor)
+		assert.Equal(t, []Mismatch{
+			{"dolt-1", "mydb", "main", MismatchWorkingSet, "standby working set is w2, primary working set is w3"},
+		}, res)
+	})
+}
+
+func TestRenderMismatches(t *testing.T) {
+	var buf bytes.Buffer
+	RenderMismatches(&buf, []Mismatch{
+		{"dolt-1", "mydb", "", MismatchMissing, "database is not on the standby"},
+		{"dolt-2", "mydb", "main", MismatchBehind, "standby is at c2, primary is at c3"},
+	})
+	assert.Equal(t, `POD     DATABASE  BRANCH  PROBLEM  DETAIL
+dolt-1  mydb      -       missing  database is not on the standby
+dolt-2  mydb      main    behind   standby is at c2, primary is at c3
+`, buf.String())
+}
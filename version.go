@@ -43,3 +43,26 @@ func VersionSupportsTransitionToStandby(version string) bool {
 	}
 	return true
 }
+
+// A feature of Dolt which doltclusterctl makes use of, and the versions
+// which support it.
+type versionCapability struct {
+	Name      string
+	Supported func(version string) bool
+}
+
+var versionCapabilities = []versionCapability{
+	{"dolt_cluster_transition_to_standby", VersionSupportsTransitionToStandby},
+}
+
+// Returns the names of the capabilities which |oldVersion| supports but
+// |newVersion| does not.
+func DroppedCapabilities(oldVersion, newVersion string) []string {
+	var res []string
+	for _, c := range versionCapabilities {
+		if c.Supported(oldVersion) && !c.Supported(newVersion) {
+			res = append(res, c.Name)
+		}
+	}
+	return res
+}
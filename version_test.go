@@ -44,3 +44,12 @@ func TestVersionSupportsTransitionToStandby(t *testing.T) {
 		})
 	}
 }
+
+func TestDroppedCapabilities(t *testing.T) {
+	if dropped := DroppedCapabilities("1.5.0", "1.6.0"); len(dropped) != 0 {
+		t.Fatalf("expected no dropped capabilities upgrading from 1.5.0 to 1.6.0, but got %v", dropped)
+	}
+	if dropped := DroppedCapabilities("1.6.0", "1.5.0"); len(dropped) != 1 || dropped[0] != "dolt_cluster_transition_to_standby" {
+		t.Fatalf("expected dolt_cluster_transition_to_standby to be dropped downgrading from 1.6.0 to 1.5.0, but got %v", dropped)
+	}
+}
@@ -0,0 +1,166 @@
+// Copyright 2023 DoltHub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+)
+
+// WaitForCaughtUp blocks until every standby has replicated the primary's
+// writes to within -max-lag, according to the primary's
+// dolt_cluster_status. If -timeout expires first, it prints the replication
+// status of every database and exits non-zero.
+type WaitForCaughtUp struct{}
+
+func (cmd WaitForCaughtUp) Run(ctx context.Context, cfg *Config, cluster Cluster) error {
+	rows, err := WaitForStandbysCaughtUp(ctx, cfg, cluster, cfg.MaxLag)
+	if err != nil {
+		RenderCaughtUp(os.Stdout, rows, cfg.MaxLag)
+		return err
+	}
+	log.Printf("every standby of %s is caught up across %d databases", cluster.Name(), len(rows))
+	return nil
+}
+
+// Polls the primary of |cluster| until every row of its
+// dolt_cluster_status has no current_error and a replication lag of at most
+// |maxLag|, and it reports replicating to every other instance. Returns the
+// rows it last loaded, along with an error if |ctx| was done before they
+// were caught up.
+func WaitForStandbysCaughtUp(ctx context.Context, cfg *Config, cluster Cluster, maxLag time.Duration) ([]StatusRow, error) {
+	return waitForCaughtUp(ctx, cfg, cluster, maxLag, func(dbstates []DBState, primary int) ([]StatusRow, []int) {
+		var standbys []int
+		for i := range dbstates {
+			if i != primary {
+				standbys = append(standbys, i)
+			}
+		}
+		return dbstates[primary].Status, standbys
+	})
+}
+
+// Like WaitForStandbysCaughtUp, but only waits for the standby at ordinal
+// |standby|.
+func WaitForStandbyCaughtUp(ctx context.Context, cfg *Config, cluster Cluster, standby int, maxLag time.Duration) ([]StatusRow, error) {
+	return waitForCaughtUp(ctx, cfg, cluster, maxLag, func(dbstates []DBState, primary int) ([]StatusRow, []int) {
+		return StandbyStatusRows(dbstates, primary, standby), []int{standby}
+	})
+}
+
+// |statusRows| returns the rows to check and the standbys the primary has to
+// report replicating to.
+func waitForCaughtUp(ctx context.Context, cfg *Config, cluster Cluster, maxLag time.Duration, statusRows func(dbstates []DBState, primary int) ([]StatusRow, []int)) ([]StatusRow, error) {
+	var rows []StatusRow
+	lastErr := errors.New("did not load the replication status of the primary")
+	for {
+		dbstates := LoadDBStates(ctx, cfg, cluster)
+		if ctx.Err() == nil {
+			primary, _, err := CurrentPrimaryAndEpoch(dbstates)
+			if err != nil {
+				lastErr = err
+			} else {
+				var standbys []int
+				rows, standbys = statusRows(dbstates, primary)
+				lastErr = nil
+				for _, row := range rows {
+					if problem := CaughtUpProblem(row, maxLag); problem != "" {
+						lastErr = fmt.Errorf("database %s on %s is not caught up: %s", row.Database, row.Remote, problem)
+						break
+					}
+				}
+				for _, standby := range standbys {
+					if lastErr != nil {
+						break
+					}
+					if problem := StandbyCaughtUpProblem(dbstates, primary, standby, maxLag); problem != "" {
+						lastErr = fmt.Errorf("%s is not caught up: %s", dbstates[standby].Instance.Name(), problem)
+					}
+				}
+				if lastErr == nil {
+					return rows, nil
+				}
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return rows, fmt.Errorf("standbys of %s did not catch up: %w", cluster.Name(), lastErr)
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// Returns why the primary at |primary| does not report the standby at
+// |standby| as caught up to within |maxLag| on every database, or "" if it
+// does. A standby the primary reports no replication to while it replicates
+// some database is not caught up: the primary has not loaded its status yet,
+// or its standby remotes do not reach it.
+func StandbyCaughtUpProblem(dbstates []DBState, primary, standby int, maxLag time.Duration) string {
+	rows := StandbyStatusRows(dbstates, primary, standby)
+	if len(rows) == 0 && len(dbstates[primary].Status) > 0 {
+		return fmt.Sprintf("%s does not report replicating to it", dbstates[primary].Instance.Name())
+	}
+	for _, row := range rows {
+		if problem := CaughtUpProblem(row, maxLag); problem != "" {
+			return fmt.Sprintf("database %s: %s", row.Database, problem)
+		}
+	}
+	return ""
+}
+
+// Returns why the standby replicating |row| is not caught up to within
+// |maxLag|, or "" if it is.
+func CaughtUpProblem(row StatusRow, maxLag time.Duration) string {
+	if row.CurrentError.Valid {
+		return "replication has error: " + row.CurrentError.String
+	}
+	if !row.ReplicationLag.Valid {
+		return "replication lag is unknown"
+	}
+	if row.ReplicationLag.Int64 > maxLag.Milliseconds() {
+		return fmt.Sprintf("replication lag is %dms", row.ReplicationLag.Int64)
+	}
+	return ""
+}
+
+// Writes a table of the replication status of every database in |rows|,
+// the dolt_cluster_status rows of the primary, to |w|.
+func RenderCaughtUp(w io.Writer, rows []StatusRow, maxLag time.Duration) {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "DATABASE\tREMOTE\tLAG_MILLIS\tCURRENT_ERROR\tCAUGHT_UP")
+	for _, row := range rows {
+		lag := "-"
+		if row.ReplicationLag.Valid {
+			lag = strconv.FormatInt(row.ReplicationLag.Int64, 10)
+		}
+		currentError := "-"
+		if row.CurrentError.Valid {
+			currentError = row.CurrentError.String
+		}
+		caughtUp := "yes"
+		if CaughtUpProblem(row, maxLag) != "" {
+			caughtUp = "no"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", row.Database, row.Remote, lag, currentError, caughtUp)
+	}
+	tw.Flush()
+}
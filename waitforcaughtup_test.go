@@ -0,0 +1,99 @@
+// Copyright 2023 DoltHub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCaughtUpProblem(t *testing.T) {
+	caughtUp := StatusRow{Database: "mydb", Remote: "dolt-1", ReplicationLag: sql.NullInt64{Valid: true, Int64: 0}}
+	assert.Equal(t, "", CaughtUpProblem(caughtUp, 0))
+
+	lagging := StatusRow{Database: "mydb", Remote: "dolt-1", ReplicationLag: sql.NullInt64{Valid: true, Int64: 250}}
+	assert.Equal(t, "replication lag is 250ms", CaughtUpProblem(lagging, 0))
+	assert.Equal(t, "", CaughtUpProblem(lagging, time.Second))
+
+	unknown := StatusRow{Database: "mydb", Remote: "dolt-1"}
+	assert.Equal(t, "replication lag is unknown", CaughtUpProblem(unknown, time.Second))
+
+	errored := StatusRow{Database: "mydb", Remote: "dolt-1", ReplicationLag: sql.NullInt64{Valid: true, Int64: 0}, CurrentError: sql.NullString{Valid: true, String: "connection refused"}}
+	assert.Equal(t, "replication has error: connection refused", CaughtUpProblem(errored, time.Second))
+}
+
+func TestStandbyCaughtUpProblem(t *testing.T) {
+	dbstates := func() []DBState {
+		return []DBState{{
+			Role:     "primary",
+			Instance: &mockInstance{name: "dolt-0", hostname: "dolt-0.dolt-internal.default"},
+			Status: []StatusRow{{
+				Database:       "mydb",
+				Remote:         "dolt-1",
+				ReplicationLag: sql.NullInt64{Valid: true, Int64: 0},
+			}},
+			Remotes: []DBRemote{{
+				Database: "mydb",
+				Name:     "dolt-1",
+				URL:      "http://dolt-1.dolt-internal:50051/mydb",
+			}},
+		}, {
+			Role:     "standby",
+			Instance: &mockInstance{name: "dolt-1", hostname: "dolt-1.dolt-internal.default"},
+		}, {
+			Role:     "standby",
+			Instance: &mockInstance{name: "dolt-2", hostname: "dolt-2.dolt-internal.default"},
+		}}
+	}
+	t.Run("CaughtUp", func(t *testing.T) {
+		assert.Equal(t, "", StandbyCaughtUpProblem(dbstates(), 0, 1, 0))
+	})
+	t.Run("Lagging", func(t *testing.T) {
+		states := dbstates()
+		states[0].Status[0].ReplicationLag.Int64 = 250
+		assert.Equal(t, "database mydb: replication lag is 250ms", StandbyCaughtUpProblem(states, 0, 1, 0))
+	})
+	t.Run("NotReported", func(t *testing.T) {
+		assert.Equal(t, "dolt-0 does not report replicating to it", StandbyCaughtUpProblem(dbstates(), 0, 2, time.Second))
+	})
+	t.Run("NoDatabases", func(t *testing.T) {
+		states := dbstates()
+		states[0].Status = nil
+		states[0].Remotes = nil
+		assert.Equal(t, "", StandbyCaughtUpProblem(states, 0, 2, 0))
+	})
+}
+
+func TestRenderCaughtUp(t *testing.T) {
+	var buf bytes.Buffer
+	RenderCaughtUp(&buf, []StatusRow{{
+		Database:       "mydb",
+		Remote:         "dolt-1",
+		ReplicationLag: sql.NullInt64{Valid: true, Int64: 0},
+	}, {
+		Database:       "mydb",
+		Remote:         "dolt-2",
+		ReplicationLag: sql.NullInt64{Valid: true, Int64: 2000},
+		CurrentError:   sql.NullString{Valid: true, String: "failed"},
+	}}, time.Second)
+	assert.Equal(t, `DATABASE  REMOTE  LAG_MILLIS  CURRENT_ERROR  CAUGHT_UP
+mydb      dolt-1  0           -              yes
+mydb      dolt-2  2000        failed         no
+`, buf.String())
+}